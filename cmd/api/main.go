@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +15,7 @@ import (
 	"github.com/Siddharth9890/osquery-mvp/internal/database"
 	api "github.com/Siddharth9890/osquery-mvp/internal/handler"
 	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	"github.com/Siddharth9890/osquery-mvp/internal/scheduler"
 	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
 	"github.com/Siddharth9890/osquery-mvp/pkg/middleware"
 	"github.com/Siddharth9890/osquery-mvp/ui"
@@ -40,42 +43,187 @@ func main() {
 	log.Info("Configuration loaded",
 		zap.String("db_name", cfg.DBName),
 		zap.String("api_port", cfg.APIPort),
-		zap.Duration("refresh_interval", cfg.RefreshInterval))
+		zap.Duration("refresh_interval", cfg.RefreshInterval),
+		zap.String("osquery_backend", cfg.OsqueryBackend))
 
-	if err := osquery.CheckOsqueryInstallation(); err != nil {
-		log.Fatal("Osquery check failed",
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, cfg, os.Args[2:]); err != nil {
+			log.Fatal("Migration command failed",
+				zap.Error(err))
+		}
+		return
+	}
+
+	minVersion, err := osquery.ParseVersion(cfg.OsqueryMinVersion)
+	if err != nil {
+		log.Fatal("Invalid minimum osquery version",
+			zap.String("min_version", cfg.OsqueryMinVersion),
 			zap.Error(err))
 	}
-	log.Info("Osquery installation verified successfully")
 
-	log.Debug("Connecting to database...")
-	dbConn, err := config.NewDatabaseConnection(cfg.GetDBConnectionString())
+	if cfg.OsqueryBackend == osquery.BackendOsqueryi || cfg.OsqueryBackend == osquery.BackendSession {
+		install, err := osquery.CheckOsqueryInstallation(ctx, cfg.OsqueryBinaryPath, minVersion)
+		if err != nil {
+			log.Fatal("Osquery check failed",
+				zap.Error(err))
+		}
+		log.Info("Osquery installation verified successfully",
+			zap.String("binary_path", install.BinaryPath),
+			zap.String("version", install.Version.String()),
+			zap.String("build_platform", install.BuildPlatform),
+			zap.String("build_distro", install.BuildDistro))
+	}
+
+	log.Debug("Connecting to database...",
+		zap.String("driver", cfg.DBDriver))
+	dbService, err := config.NewStore(cfg)
 	if err != nil {
 		log.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer dbConn.Close()
+	defer dbService.Close()
+
+	if migrator, ok := dbService.(database.Migrator); ok && cfg.DBAutoMigrate {
+		log.Info("Applying database migrations")
+		if err := migrator.MigrateUp(ctx); err != nil {
+			log.Fatal("Failed to migrate database", zap.Error(err))
+		}
+	}
+
+	backend, err := osquery.NewQuerier(osquery.QuerierConfig{
+		Backend:        cfg.OsqueryBackend,
+		BinaryPath:     cfg.OsqueryBinaryPath,
+		FixturePath:    cfg.OsqueryFixturePath,
+		SocketPath:     cfg.OsquerySocketPath,
+		QueryTimeout:   cfg.OsqueryQueryTimeout,
+		MaxOutputBytes: cfg.OsqueryMaxOutputBytes,
+	})
+	if err != nil {
+		log.Fatal("Failed to create osquery backend",
+			zap.Error(err))
+	}
+
+	if closer, ok := backend.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if extension, ok := backend.(*osquery.ExtensionQuerier); ok {
+		if err := extension.Ping(ctx); err != nil {
+			log.Warn("Osquery extension manager did not answer ping",
+				zap.String("socket_path", cfg.OsquerySocketPath),
+				zap.Error(err))
+		}
+	}
+
+	var recorder *osquery.RecordingQuerier
+	if cfg.OsqueryRecordPath != "" {
+		recorder = osquery.NewRecordingQuerier(backend)
+		backend = recorder
+	}
+
+	querier := osquery.NewOsqueryClient(backend)
+	querier.SetCertificateDirs(cfg.CertificateDirs)
+	querier.SetFileIntegrityPaths(cfg.FileIntegrityPaths)
+	querier.SetCollectExtensions(cfg.CollectExtensions)
+
+	var sysctlBaseline osquery.SysctlBaseline
+	if cfg.SysctlBaselinePath != "" {
+		sysctlBaseline, err = osquery.LoadSysctlBaseline(cfg.SysctlBaselinePath)
+		if err != nil {
+			log.Fatal("Failed to load sysctl baseline",
+				zap.String("baseline_path", cfg.SysctlBaselinePath),
+				zap.Error(err))
+		}
+		log.Info("Loaded sysctl baseline",
+			zap.String("baseline_path", cfg.SysctlBaselinePath),
+			zap.Int("sysctl_count", len(sysctlBaseline)))
+		querier.SetSysctlBaseline(sysctlBaseline)
+	}
 
-	dbService := database.NewService(dbConn)
+	version, err := querier.DetectVersion(ctx)
+	if err != nil {
+		log.Warn("Failed to detect osquery version, version-gated collectors will run unchecked",
+			zap.Error(err))
+	} else if err := osquery.RequireVersion(version, minVersion); err != nil {
+		log.Fatal("Osquery backend version check failed",
+			zap.Error(err))
+	}
 
-	querier := osquery.NewOsqueryClient()
+	tableCount, err := querier.DiscoverTables(ctx)
+	if err != nil {
+		log.Warn("Failed to discover osquery tables, assuming all tables are available",
+			zap.Error(err))
+	} else {
+		log.Info("Discovered osquery tables",
+			zap.Int("table_count", tableCount))
+	}
 
 	log.Info("Running initial data collection...")
-	if err := collectAndStoreData(querier, dbService); err != nil {
+	if err := collectAndStoreData(ctx, querier, dbService); err != nil {
 		log.Error("Error in initial data collection",
 			zap.Error(err))
 	}
 
+	if cfg.OsqueryPacksDir != "" {
+		packs, err := osquery.LoadPacks(cfg.OsqueryPacksDir)
+		if err != nil {
+			log.Fatal("Failed to load osquery packs",
+				zap.String("packs_dir", cfg.OsqueryPacksDir),
+				zap.Error(err))
+		}
+		log.Info("Loaded osquery packs",
+			zap.String("packs_dir", cfg.OsqueryPacksDir),
+			zap.Int("pack_count", len(packs)))
+
+		packStore, ok := dbService.(database.PackStore)
+		if !ok {
+			log.Fatal("Database driver cannot store pack results",
+				zap.String("driver", cfg.DBDriver))
+		}
+
+		go scheduler.NewPackScheduler(querier, packStore, packs).Run(ctx)
+	}
+
 	requestIDMiddleware := middleware.RequestIDMiddleware
 
-	apiHandler := api.NewHandler(dbService)
+	apiHandler := api.NewHandler(dbService, api.Options{
+		CertificateExpiryWindow: cfg.CertificateExpiryWindow,
+		DiskUsageThreshold:      cfg.DiskUsageThreshold,
+		InodeUsageThreshold:     cfg.InodeUsageThreshold,
+		SysctlBaseline:          sysctlBaseline,
+	})
 	http.Handle("/api/latest_data", requestIDMiddleware(http.HandlerFunc(apiHandler.GetLatestData)))
+	http.Handle("/api/pack_results", requestIDMiddleware(http.HandlerFunc(apiHandler.GetPackResults)))
+	http.Handle("/api/query_events", requestIDMiddleware(http.HandlerFunc(apiHandler.GetQueryEvents)))
+	http.Handle("/api/listening_ports", requestIDMiddleware(http.HandlerFunc(apiHandler.GetListeningPorts)))
+	http.Handle("/api/sudo_accounts", requestIDMiddleware(http.HandlerFunc(apiHandler.GetSudoAccounts)))
+	http.Handle("/api/authorized_keys", requestIDMiddleware(http.HandlerFunc(apiHandler.GetAuthorizedKeys)))
+	http.Handle("/api/process_tree", requestIDMiddleware(http.HandlerFunc(apiHandler.GetProcessTree)))
+	http.Handle("/api/persistence", requestIDMiddleware(http.HandlerFunc(apiHandler.GetPersistence)))
+	http.Handle("/api/certificates/expiring", requestIDMiddleware(http.HandlerFunc(apiHandler.GetExpiringCertificates)))
+	http.Handle("/api/file_changes", requestIDMiddleware(http.HandlerFunc(apiHandler.GetFileChanges)))
+	http.Handle("/api/mounts", requestIDMiddleware(http.HandlerFunc(apiHandler.GetMounts)))
+	http.Handle("/api/mounts/history", requestIDMiddleware(http.HandlerFunc(apiHandler.GetMountHistory)))
+	http.Handle("/api/kernel_modules", requestIDMiddleware(http.HandlerFunc(apiHandler.GetKernelModules)))
+	http.Handle("/api/system_controls", requestIDMiddleware(http.HandlerFunc(apiHandler.GetSystemControls)))
+	http.Handle("/api/sysctl_deviations", requestIDMiddleware(http.HandlerFunc(apiHandler.GetSysctlDeviations)))
+	http.Handle("/api/containers", requestIDMiddleware(http.HandlerFunc(apiHandler.GetContainers)))
+	http.Handle("/api/images", requestIDMiddleware(http.HandlerFunc(apiHandler.GetImages)))
+	http.Handle("/api/extensions", requestIDMiddleware(http.HandlerFunc(apiHandler.GetExtensions)))
+	http.Handle("/api/extensions/installs", requestIDMiddleware(http.HandlerFunc(apiHandler.FindExtensionInstalls)))
+	http.Handle("/api/snapshots", requestIDMiddleware(http.HandlerFunc(apiHandler.GetSnapshots)))
+	http.Handle("/api/snapshots/diff", requestIDMiddleware(http.HandlerFunc(apiHandler.DiffSnapshots)))
 
-	uiHandler, err := ui.NewHandler(dbService, "http://localhost:"+cfg.APIPort+"/api")
+	uiHandler, err := ui.NewHandler("http://localhost:" + cfg.APIPort + "/api")
 	if err != nil {
 		log.Fatal("Failed to create UI handler",
 			zap.Error(err))
 	}
 	http.Handle("/", requestIDMiddleware(http.HandlerFunc(uiHandler.Dashboard)))
+	http.Handle("/processes", requestIDMiddleware(http.HandlerFunc(uiHandler.Processes)))
+	http.Handle("/containers", requestIDMiddleware(http.HandlerFunc(uiHandler.Containers)))
 	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("ui/assets"))))
 
 	go func() {
@@ -94,56 +242,103 @@ func main() {
 	ticker := time.NewTicker(cfg.RefreshInterval)
 	defer ticker.Stop()
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
 	for {
 		select {
 		case <-ticker.C:
 			log.Info("Running scheduled data collection...")
-			if err := collectAndStoreData(querier, dbService); err != nil {
+			if err := collectAndStoreData(ctx, querier, dbService); err != nil {
 				log.Error("Error in scheduled data collection",
 					zap.Error(err))
 			}
-		case <-stop:
+		case <-ctx.Done():
 			log.Info("Shutting down...")
+			if recorder != nil {
+				if err := recorder.Save(cfg.OsqueryRecordPath); err != nil {
+					log.Error("Failed to save recorded osquery fixtures",
+						zap.Error(err))
+				}
+			}
 			return
 		}
 	}
 }
 
-func collectAndStoreData(querier *osquery.OsqueryClient, dbService *database.Service) error {
+func collectAndStoreData(ctx context.Context, querier *osquery.OsqueryClient, dbService database.Store) error {
 	log := logger.Log
 
-	log.Debug("Querying system information from osquery")
-	sysInfo, err := querier.GetSystemInfo()
+	log.Debug("Collecting snapshot from osquery")
+	snapshot, err := querier.CollectSnapshot(ctx)
 	if err != nil {
-		log.Error("Failed to get system information from osquery",
+		log.Error("Failed to collect snapshot from osquery",
 			zap.Error(err))
 		return err
 	}
 
-	log.Debug("Querying installed applications from osquery")
-	apps, err := querier.GetInstalledApps()
-	if err != nil {
-		log.Error("Failed to get installed applications from osquery",
-			zap.Error(err))
-		return err
+	for _, collector := range snapshot.Skipped {
+		log.Debug("Skipped collector",
+			zap.String("collector", collector.Name),
+			zap.String("reason", collector.Reason))
 	}
 
 	log.Debug("Storing collected data in database",
-		zap.Int("app_count", len(apps)))
-	if err := dbService.StoreSystemInfo(sysInfo, apps); err != nil {
+		zap.Int("app_count", len(snapshot.Apps)),
+		zap.Int("listening_port_count", len(snapshot.ListeningPorts)))
+	if err := dbService.StoreSnapshot(snapshot); err != nil {
 		log.Error("Failed to store data in database",
 			zap.Error(err))
 		return err
 	}
 
+	sysInfo := snapshot.SystemInfo
 	log.Info("Data collection completed successfully",
+		zap.String("hostname", sysInfo.Hostname),
+		zap.String("hardware_uuid", sysInfo.HardwareUUID),
 		zap.String("os_version", sysInfo.OSVersion),
 		zap.String("os_name", sysInfo.OSName),
 		zap.String("os_platform", sysInfo.OSPlatform),
 		zap.String("osquery_version", sysInfo.OsqueryVersion),
-		zap.Int("app_count", len(apps)))
+		zap.Int("app_count", len(snapshot.Apps)),
+		zap.Int("listening_port_count", len(snapshot.ListeningPorts)),
+		zap.Int("user_count", len(snapshot.Accounts.Users)),
+		zap.Int("process_count", len(snapshot.Processes)),
+		zap.Int("certificate_count", len(snapshot.Certificates)),
+		zap.Int("watched_file_count", len(snapshot.Files)),
+		zap.Int("mount_count", len(snapshot.Mounts)),
+		zap.Int("kernel_module_count", len(snapshot.KernelModules)),
+		zap.Int("container_count", len(snapshot.Docker.Containers)),
+		zap.Int("extension_count", len(snapshot.Extensions)),
+		zap.Int("skipped_collectors", len(snapshot.Skipped)))
+
+	if inventory, ok := dbService.(database.InventoryStore); ok {
+		reportNewPersistence(inventory)
+	}
 	return nil
 }
+
+// reportNewPersistence warns about persistence mechanisms that appeared since
+// the previous snapshot.
+func reportNewPersistence(inventory database.InventoryStore) {
+	persistence, err := inventory.GetLatestPersistence()
+	if err != nil {
+		logger.Log.Error("Failed to compare persistence with previous snapshot",
+			zap.Error(err))
+		return
+	}
+
+	persistence = persistence.OnlyNew()
+	for _, unit := range persistence.SystemdUnits {
+		logger.Log.Warn("New systemd unit",
+			zap.String("unit", unit.ID),
+			zap.String("fragment_path", unit.FragmentPath))
+	}
+	for _, job := range persistence.CronJobs {
+		logger.Log.Warn("New cron job",
+			zap.String("path", job.Path),
+			zap.String("command", job.Command))
+	}
+	for _, item := range persistence.StartupItems {
+		logger.Log.Warn("New startup item",
+			zap.String("name", item.Name),
+			zap.String("path", item.Path))
+	}
+}
@@ -25,6 +25,20 @@ func NewDatabaseConnection(dsn string) (*sql.DB, error) {
 	return conn, nil
 }
 
-func NewDatabaseService(conn *sql.DB) *database.Service {
-	return database.NewService(conn)
+// NewStore opens the storage backend selected by DB_DRIVER.
+func NewStore(c *Config) (database.Store, error) {
+	switch c.DBDriver {
+	case "mysql":
+		conn, err := NewDatabaseConnection(c.GetDBConnectionString())
+		if err != nil {
+			return nil, err
+		}
+		return database.NewService(conn), nil
+	case "postgres":
+		return database.NewPostgresService(c.GetPostgresConnectionString())
+	case "sqlite":
+		return database.NewSQLiteService(c.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q (expected mysql, postgres or sqlite)", c.DBDriver)
+	}
 }
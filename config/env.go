@@ -2,37 +2,99 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	DBDriver      string
+	SQLitePath    string
+	DBAutoMigrate bool
+
 	DBUser     string
 	DBPassword string
 	DBHost     string
 	DBPort     string
 	DBName     string
+	DBSSLMode  string
 
 	APIPort         string
 	RefreshInterval time.Duration
+
+	OsqueryBackend     string
+	OsqueryBinaryPath  string
+	OsqueryFixturePath string
+	OsqueryRecordPath  string
+	OsquerySocketPath  string
+	OsqueryMinVersion  string
+	OsqueryPacksDir    string
+
+	OsqueryQueryTimeout   time.Duration
+	OsqueryMaxOutputBytes int
+
+	CertificateDirs         []string
+	CertificateExpiryWindow time.Duration
+
+	FileIntegrityPaths []string
+
+	DiskUsageThreshold  float64
+	InodeUsageThreshold float64
+
+	SysctlBaselinePath string
+
+	CollectExtensions bool
 }
 
 func LoadConfig() (*Config, error) {
 	godotenv.Load()
 
 	config := &Config{
+		DBDriver:      getEnv("DB_DRIVER", "mysql"),
+		SQLitePath:    getEnv("SQLITE_PATH", "osquery.db"),
+		DBAutoMigrate: getEnvAsBool("DB_AUTO_MIGRATE", true),
+
 		DBUser:     getEnv("DB_USER", "osquery"),
 		DBPassword: getEnv("DB_PASSWORD", "osquery"),
 		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "3306"),
 		DBName:     getEnv("DB_NAME", "osquery_data"),
+		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
 		APIPort: getEnv("API_PORT", "8080"),
+
+		OsqueryBackend:     getEnv("OSQUERY_BACKEND", "osqueryi"),
+		OsqueryBinaryPath:  getEnv("OSQUERY_BINARY_PATH", "osqueryi"),
+		OsqueryFixturePath: getEnv("OSQUERY_FIXTURE_PATH", ""),
+		OsqueryRecordPath:  getEnv("OSQUERY_RECORD_PATH", ""),
+		OsquerySocketPath:  getEnv("OSQUERY_EXTENSION_SOCKET", "/var/osquery/osquery.em"),
+		OsqueryMinVersion:  getEnv("OSQUERY_MIN_VERSION", "4.0.0"),
+		OsqueryPacksDir:    getEnv("OSQUERY_PACKS_DIR", ""),
+
+		OsqueryMaxOutputBytes: getEnvAsInt("OSQUERY_MAX_OUTPUT_BYTES", 32<<20),
+
+		CertificateDirs: getEnvAsList("CERTIFICATE_DIRS"),
+
+		FileIntegrityPaths: getEnvAsList("FIM_PATHS"),
+
+		DiskUsageThreshold:  getEnvAsFloat("DISK_USAGE_THRESHOLD", 90),
+		InodeUsageThreshold: getEnvAsFloat("INODE_USAGE_THRESHOLD", 90),
+
+		SysctlBaselinePath: getEnv("SYSCTL_BASELINE_PATH", ""),
+
+		CollectExtensions: getEnvAsBool("COLLECT_EXTENSIONS", false),
 	}
 
+	defaultDBPort := "3306"
+	if config.DBDriver == "postgres" {
+		defaultDBPort = "5432"
+	}
+	config.DBPort = getEnv("DB_PORT", defaultDBPort)
+
 	refreshStr := getEnv("REFRESH_INTERVAL", "15m")
 	refreshInterval, err := time.ParseDuration(refreshStr)
 	if err != nil {
@@ -40,6 +102,20 @@ func LoadConfig() (*Config, error) {
 	}
 	config.RefreshInterval = refreshInterval
 
+	queryTimeoutStr := getEnv("OSQUERY_QUERY_TIMEOUT", "30s")
+	queryTimeout, err := time.ParseDuration(queryTimeoutStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid osquery query timeout format: %v", err)
+	}
+	config.OsqueryQueryTimeout = queryTimeout
+
+	expiryWindowStr := getEnv("CERTIFICATE_EXPIRY_WINDOW", "720h")
+	expiryWindow, err := time.ParseDuration(expiryWindowStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate expiry window format: %v", err)
+	}
+	config.CertificateExpiryWindow = expiryWindow
+
 	return config, nil
 }
 
@@ -48,6 +124,17 @@ func (c *Config) GetDBConnectionString() string {
 		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
 }
 
+func (c *Config) GetPostgresConnectionString() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     net.JoinHostPort(c.DBHost, c.DBPort),
+		Path:     "/" + c.DBName,
+		RawQuery: url.Values{"sslmode": {c.DBSSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
 func (c *Config) GetAPIAddress() string {
 	return ":" + c.APIPort
 }
@@ -67,3 +154,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsList splits a comma-separated variable, dropping empty entries.
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -2,9 +2,23 @@ module github.com/Siddharth9890/osquery-mvp
 
 go 1.21.6
 
-require github.com/go-sql-driver/mysql v1.9.1
+require (
+	github.com/go-sql-driver/mysql v1.9.1
+	github.com/lib/pq v1.10.9
+	modernc.org/sqlite v1.34.5
+)
 
-require go.uber.org/multierr v1.10.0 // indirect
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
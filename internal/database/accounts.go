@@ -0,0 +1,165 @@
+package database
+
+import (
+	"fmt"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeAccounts(tx *dialectTx, systemInfoID int64, accounts osquery.Accounts) error {
+	for _, user := range accounts.Users {
+		if _, err := tx.Exec(
+			"INSERT INTO local_users (system_info_id, uid, gid, username, description, directory, shell, uuid) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			systemInfoID, user.UID, user.GID, user.Username, user.Description, user.Directory, user.Shell, user.UUID,
+		); err != nil {
+			return fmt.Errorf("database insert error for user '%s': %w", user.Username, err)
+		}
+	}
+
+	for _, group := range accounts.Groups {
+		if _, err := tx.Exec(
+			"INSERT INTO local_groups (system_info_id, gid, groupname) VALUES (?, ?, ?)",
+			systemInfoID, group.GID, group.GroupName,
+		); err != nil {
+			return fmt.Errorf("database insert error for group '%s': %w", group.GroupName, err)
+		}
+	}
+
+	for _, membership := range accounts.UserGroups {
+		if _, err := tx.Exec(
+			"INSERT INTO user_groups (system_info_id, uid, gid) VALUES (?, ?, ?)",
+			systemInfoID, membership.UID, membership.GID,
+		); err != nil {
+			return fmt.Errorf("database insert error for user group membership: %w", err)
+		}
+	}
+
+	for _, session := range accounts.LoggedInUsers {
+		if _, err := tx.Exec(
+			`INSERT INTO logged_in_users (system_info_id, type, "user", tty, host, login_time, pid) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, session.Type, session.User, session.TTY, session.Host, session.Time, session.PID,
+		); err != nil {
+			return fmt.Errorf("database insert error for logged in user '%s': %w", session.User, err)
+		}
+	}
+
+	for _, key := range accounts.AuthorizedKeys {
+		if _, err := tx.Exec(
+			"INSERT INTO authorized_keys (system_info_id, uid, username, algorithm, key_data, key_file, comment, fingerprint) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			systemInfoID, key.UID, key.Username, key.Algorithm, key.Key, key.KeyFile, key.Comment, key.Fingerprint,
+		); err != nil {
+			return fmt.Errorf("database insert error for authorized key of '%s': %w", key.Username, err)
+		}
+	}
+
+	for _, rule := range accounts.SudoersRules {
+		if _, err := tx.Exec(
+			"INSERT INTO sudoers_rules (system_info_id, source, header, rule_details) VALUES (?, ?, ?, ?)",
+			systemInfoID, rule.Source, rule.Header, rule.RuleDetails,
+		); err != nil {
+			return fmt.Errorf("database insert error for sudoers rule: %w", err)
+		}
+	}
+
+	for _, account := range accounts.SudoAccounts {
+		if _, err := tx.Exec(
+			"INSERT INTO sudo_accounts (system_info_id, username, uid, via, source, rule) VALUES (?, ?, ?, ?, ?, ?)",
+			systemInfoID, account.Username, account.UID, account.Via, account.Source, account.Rule,
+		); err != nil {
+			return fmt.Errorf("database insert error for sudo account '%s': %w", account.Username, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSudoAccounts lists every sudo grant ever seen on the latest snapshot's
+// host, by hardware_uuid, with the first and last snapshot it appeared in.
+// Unless includeGone is set only grants present in the latest snapshot are
+// returned.
+func (s *Service) GetSudoAccounts(includeGone bool) ([]model.SudoAccountRecord, error) {
+	latestID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT sa.username, sa.uid, sa.via, sa.source, sa.rule,
+			MIN(si.collected_at), MAX(si.collected_at), MAX(si.id)
+		FROM sudo_accounts sa
+		JOIN system_info si ON si.id = sa.system_info_id
+		WHERE si.hardware_uuid = (SELECT hardware_uuid FROM system_info WHERE id = ?)
+		GROUP BY sa.username, sa.uid, sa.via, sa.source, sa.rule
+		ORDER BY sa.username, sa.via
+	`, latestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sudo accounts: %w", err)
+	}
+	defer rows.Close()
+
+	records := []model.SudoAccountRecord{}
+	for rows.Next() {
+		var record model.SudoAccountRecord
+		var lastID int64
+		if err := rows.Scan(&record.Username, &record.UID, &record.Via, &record.Source, &record.Rule,
+			timestamp{&record.FirstSeen}, timestamp{&record.LastSeen}, &lastID); err != nil {
+			return nil, fmt.Errorf("failed to scan sudo account row: %w", err)
+		}
+
+		record.Present = lastID == latestID
+		if record.Present || includeGone {
+			records = append(records, record)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over sudo account rows: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *Service) GetAuthorizedKeys(includeGone bool) ([]model.AuthorizedKeyRecord, error) {
+	latestID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT ak.uid, ak.username, ak.fingerprint, ak.key_file,
+			MAX(ak.algorithm), MAX(ak.key_data), MAX(ak.comment),
+			MIN(si.collected_at), MAX(si.collected_at), MAX(si.id)
+		FROM authorized_keys ak
+		JOIN system_info si ON si.id = ak.system_info_id
+		WHERE si.hardware_uuid = (SELECT hardware_uuid FROM system_info WHERE id = ?)
+		GROUP BY ak.uid, ak.username, ak.fingerprint, ak.key_file
+		ORDER BY ak.username, ak.key_file
+	`, latestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authorized keys: %w", err)
+	}
+	defer rows.Close()
+
+	records := []model.AuthorizedKeyRecord{}
+	for rows.Next() {
+		var record model.AuthorizedKeyRecord
+		var lastID int64
+		if err := rows.Scan(&record.UID, &record.Username, &record.Fingerprint, &record.KeyFile,
+			&record.Algorithm, &record.Key, &record.Comment,
+			timestamp{&record.FirstSeen}, timestamp{&record.LastSeen}, &lastID); err != nil {
+			return nil, fmt.Errorf("failed to scan authorized key row: %w", err)
+		}
+
+		record.Present = lastID == latestID
+		if record.Present || includeGone {
+			records = append(records, record)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over authorized key rows: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeCertificates(tx *dialectTx, systemInfoID int64, certificates []osquery.Certificate) error {
+	for _, certificate := range certificates {
+		_, err := tx.Exec(
+			`INSERT INTO certificates (
+				system_info_id, common_name, subject, issuer, serial, sha1,
+				not_valid_before, not_valid_after, ca, path
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, certificate.CommonName, certificate.Subject, certificate.Issuer, certificate.Serial, certificate.SHA1,
+			certificate.NotValidBefore, certificate.NotValidAfter, certificate.CA, certificate.Path,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for certificate '%s': %w", certificate.CommonName, err)
+		}
+	}
+	return nil
+}
+
+// GetExpiringCertificates returns the latest snapshot's certificates that
+// expire before now+within, including ones already expired, soonest first.
+func (s *Service) GetExpiringCertificates(within time.Duration) ([]model.CertificateRecord, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rows, err := s.db.Query(`
+		SELECT common_name, subject, issuer, serial, sha1,
+			not_valid_before, not_valid_after, ca, path
+		FROM certificates
+		WHERE system_info_id = ? AND not_valid_after <= ?
+		ORDER BY not_valid_after, common_name
+	`, systemInfoID, now.Add(within).Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring certificates: %w", err)
+	}
+	defer rows.Close()
+
+	certificates := []model.CertificateRecord{}
+	for rows.Next() {
+		var record model.CertificateRecord
+		if err := rows.Scan(&record.CommonName, &record.Subject, &record.Issuer, &record.Serial, &record.SHA1,
+			&record.NotValidBefore, &record.NotValidAfter, &record.CA, &record.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan certificate row: %w", err)
+		}
+
+		record.ExpiresAt = time.Unix(record.NotValidAfter, 0).UTC()
+		record.Expired = record.ExpiresAt.Before(now)
+		certificates = append(certificates, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over certificate rows: %w", err)
+	}
+
+	return certificates, nil
+}
@@ -13,24 +13,30 @@ import (
 )
 
 type Service struct {
-	db *sql.DB
+	db *dialectDB
 }
 
+// NewService returns a Service backed by a MySQL connection.
 func NewService(db *sql.DB) *Service {
-	return &Service{db: db}
+	return newService(db, dialectMySQL)
+}
+
+func newService(db *sql.DB, d dialect) *Service {
+	return &Service{db: &dialectDB{DB: db, dialect: d}}
 }
 
 func (s *Service) Close() error {
 	return s.db.Close()
 }
 
-func (s *Service) StoreSystemInfo(sysInfo osquery.SystemInfoResult, apps []osquery.InstalledApp) error {
+func (s *Service) StoreSnapshot(snapshot osquery.Snapshot) (err error) {
+	sysInfo := snapshot.SystemInfo
 	log := logger.Log.With(
 		zap.String("os_version", sysInfo.OSVersion),
 		zap.String("osquery_version", sysInfo.OsqueryVersion),
 	)
 
-	log.Debug("Starting database transaction for system info storage")
+	log.Debug("Starting database transaction for snapshot storage")
 
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -49,10 +55,29 @@ func (s *Service) StoreSystemInfo(sysInfo osquery.SystemInfoResult, apps []osque
 		}
 	}()
 
+	log.Debug("Storing installed apps",
+		zap.Int("app_count", len(snapshot.Apps)))
+	softwareSetID, err := storeSoftwareSet(tx, snapshot.Apps)
+	if err != nil {
+		log.Error("Failed to store installed apps",
+			zap.Error(err))
+		return err
+	}
+
 	log.Debug("Inserting system info record")
-	result, err := tx.Exec(
-		"INSERT INTO system_info (os_version, os_name, os_platform, osquery_version) VALUES (?, ?, ?, ?)",
+	systemInfoID, err := tx.insert(
+		`INSERT INTO system_info (
+			os_version, os_name, os_platform, osquery_version,
+			os_build, os_major, os_minor, os_arch,
+			hostname, computer_name, hardware_uuid, cpu_brand, cpu_physical_cores, cpu_logical_cores,
+			physical_memory, hardware_vendor, hardware_model, hardware_serial, kernel_version, uptime_seconds,
+			kernel_arguments, kernel_path, software_set_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		sysInfo.OSVersion, sysInfo.OSName, sysInfo.OSPlatform, sysInfo.OsqueryVersion,
+		sysInfo.OSBuild, sysInfo.OSMajor, sysInfo.OSMinor, sysInfo.OSArch,
+		sysInfo.Hostname, sysInfo.ComputerName, sysInfo.HardwareUUID, sysInfo.CPUBrand, sysInfo.CPUPhysicalCores, sysInfo.CPULogicalCores,
+		sysInfo.PhysicalMemory, sysInfo.HardwareVendor, sysInfo.HardwareModel, sysInfo.HardwareSerial, sysInfo.KernelVersion, sysInfo.UptimeSeconds,
+		sysInfo.KernelArguments, sysInfo.KernelPath, softwareSetID,
 	)
 	if err != nil {
 		log.Error("Failed to insert system info record",
@@ -60,76 +85,164 @@ func (s *Service) StoreSystemInfo(sysInfo osquery.SystemInfoResult, apps []osque
 		return fmt.Errorf("database insert error: %w", err)
 	}
 
-	systemInfoID, err := result.LastInsertId()
-	if err != nil {
-		log.Error("Failed to get last insert ID",
+	log.Debug("System info record created",
+		zap.Int64("system_info_id", systemInfoID))
+
+	log.Debug("Inserting listening port records")
+	if err = storeListeningPorts(tx, systemInfoID, snapshot.ListeningPorts); err != nil {
+		log.Error("Failed to insert listening port records",
 			zap.Error(err))
-		return fmt.Errorf("database ID retrieval error: %w", err)
+		return err
 	}
 
-	log.Debug("System info record created",
-		zap.Int64("system_info_id", systemInfoID))
+	log.Debug("Inserting account records")
+	if err = storeAccounts(tx, systemInfoID, snapshot.Accounts); err != nil {
+		log.Error("Failed to insert account records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting process records")
+	if err = storeProcesses(tx, systemInfoID, snapshot.Processes); err != nil {
+		log.Error("Failed to insert process records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting persistence records")
+	if err = storePersistence(tx, systemInfoID, snapshot.Persistence); err != nil {
+		log.Error("Failed to insert persistence records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting certificate records")
+	if err = storeCertificates(tx, systemInfoID, snapshot.Certificates); err != nil {
+		log.Error("Failed to insert certificate records",
+			zap.Error(err))
+		return err
+	}
 
-	log.Debug("Inserting installed apps records")
-	for i, app := range apps {
-		_, err := tx.Exec(
-			"INSERT INTO installed_apps (system_info_id, name, version) VALUES (?, ?, ?)",
-			systemInfoID, app.Name, app.Version,
+	log.Debug("Inserting file integrity records")
+	if err = storeFileIntegrity(tx, systemInfoID, snapshot); err != nil {
+		log.Error("Failed to insert file integrity records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting mount records")
+	if err = storeMounts(tx, systemInfoID, snapshot.Mounts); err != nil {
+		log.Error("Failed to insert mount records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting kernel records")
+	if err = storeKernel(tx, systemInfoID, snapshot.KernelModules, snapshot.SystemControls); err != nil {
+		log.Error("Failed to insert kernel records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting docker records")
+	if err = storeDocker(tx, systemInfoID, snapshot.Docker); err != nil {
+		log.Error("Failed to insert docker records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting extension records")
+	if err = storeExtensions(tx, systemInfoID, snapshot.Extensions); err != nil {
+		log.Error("Failed to insert extension records",
+			zap.Error(err))
+		return err
+	}
+
+	log.Debug("Inserting skipped collector records")
+	for _, collector := range snapshot.Skipped {
+		_, err = tx.Exec(
+			"INSERT INTO skipped_collectors (system_info_id, collector, reason) VALUES (?, ?, ?)",
+			systemInfoID, collector.Name, collector.Reason,
 		)
 		if err != nil {
-			log.Error("Failed to insert app record",
+			log.Error("Failed to insert skipped collector record",
 				zap.Error(err),
-				zap.String("app_name", app.Name),
-				zap.Int("app_index", i))
-			return fmt.Errorf("database insert error for app '%s': %w", app.Name, err)
+				zap.String("collector", collector.Name))
+			return fmt.Errorf("database insert error for skipped collector '%s': %w", collector.Name, err)
 		}
 	}
 
 	log.Debug("Committing transaction")
-	if err := tx.Commit(); err != nil {
+	if err = tx.Commit(); err != nil {
 		log.Error("Failed to commit transaction",
 			zap.Error(err))
 		return fmt.Errorf("transaction commit error: %w", err)
 	}
 
-	log.Info("Successfully stored system info and apps in database",
+	log.Info("Successfully stored snapshot in database",
 		zap.Int64("system_info_id", systemInfoID))
 	return nil
 }
 
+func (s *Service) getLatestSystemInfoID() (int64, error) {
+	var id int64
+	err := s.db.QueryRow(`
+		SELECT id FROM system_info
+		ORDER BY collected_at DESC, id DESC
+		LIMIT 1
+	`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+	return id, nil
+}
+
 func (s *Service) GetLatestSystemInfo() (*model.SystemInfo, error) {
 	var info model.SystemInfo
 	err := s.db.QueryRow(`
-		SELECT id, os_version, os_name, os_platform, osquery_version, collected_at 
+		SELECT id, os_version, os_name, os_platform, osquery_version,
+			os_build, os_major, os_minor, os_arch,
+			hostname, computer_name, hardware_uuid, cpu_brand, cpu_physical_cores, cpu_logical_cores,
+			physical_memory, hardware_vendor, hardware_model, hardware_serial, kernel_version, uptime_seconds,
+			kernel_arguments, kernel_path, collected_at 
 		FROM system_info 
-		ORDER BY collected_at DESC 
+		ORDER BY collected_at DESC, id DESC
 		LIMIT 1
-	`).Scan(&info.ID, &info.OSVersion, &info.OSName, &info.OSPlatform, &info.OsqueryVersion, &info.CollectedAt)
+	`).Scan(&info.ID, &info.OSVersion, &info.OSName, &info.OSPlatform, &info.OsqueryVersion,
+		&info.OSBuild, &info.OSMajor, &info.OSMinor, &info.OSArch,
+		&info.Hostname, &info.ComputerName, &info.HardwareUUID, &info.CPUBrand, &info.CPUPhysicalCores, &info.CPULogicalCores,
+		&info.PhysicalMemory, &info.HardwareVendor, &info.HardwareModel, &info.HardwareSerial, &info.KernelVersion, &info.UptimeSeconds,
+		&info.KernelArguments, &info.KernelPath, &info.CollectedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest system info: %w", err)
 	}
 
-	rows, err := s.db.Query(`
-		SELECT name, version 
-		FROM installed_apps 
+	info.Apps, err = s.getInstalledApps(int64(info.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	skippedRows, err := s.db.Query(`
+		SELECT collector, reason
+		FROM skipped_collectors
 		WHERE system_info_id = ?
 	`, info.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get installed apps: %w", err)
+		return nil, fmt.Errorf("failed to get skipped collectors: %w", err)
 	}
-	defer rows.Close()
+	defer skippedRows.Close()
 
-	info.Apps = []osquery.InstalledApp{}
-	for rows.Next() {
-		var app osquery.InstalledApp
-		if err := rows.Scan(&app.Name, &app.Version); err != nil {
-			return nil, fmt.Errorf("failed to scan app row: %w", err)
+	info.SkippedCollectors = []osquery.SkippedCollector{}
+	for skippedRows.Next() {
+		var skipped osquery.SkippedCollector
+		if err := skippedRows.Scan(&skipped.Name, &skipped.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan skipped collector row: %w", err)
 		}
-		info.Apps = append(info.Apps, app)
+		info.SkippedCollectors = append(info.SkippedCollectors, skipped)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over app rows: %w", err)
+	if err := skippedRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over skipped collector rows: %w", err)
 	}
 
 	return &info, nil
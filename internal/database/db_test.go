@@ -0,0 +1,46 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func TestStoreSnapshotRoundTrip(t *testing.T) {
+	s := newTestService(t)
+	apps := []osquery.InstalledApp{
+		{Name: "curl", Version: "8.5.0", Source: "deb", Arch: "amd64", Vendor: "Ubuntu"},
+		{Name: "openssl", Version: "3.0.13", Source: "deb", Arch: "amd64", InstallTime: 1700000000},
+	}
+	ports := []osquery.ListeningPort{
+		{PID: 100, Port: 22, Protocol: "tcp", Family: 2, Address: "0.0.0.0", ProcessName: "sshd", ProcessPath: "/usr/sbin/sshd"},
+	}
+	snapshot := testSnapshot("uuid-1", apps, ports)
+	snapshot.Skipped = []osquery.SkippedCollector{{Name: "docker", Reason: "table docker_containers is not available"}}
+
+	id := storeSnapshot(t, s, snapshot)
+
+	info, err := s.GetLatestSystemInfo()
+	if err != nil {
+		t.Fatalf("GetLatestSystemInfo: %v", err)
+	}
+	if int64(info.ID) != id || info.Hostname != "host-uuid-1" || info.HardwareUUID != "uuid-1" ||
+		info.OsqueryVersion != "5.11.0" || info.PhysicalMemory != 8<<30 {
+		t.Fatalf("system info = %+v", info)
+	}
+	if !reflect.DeepEqual(info.Apps, apps) {
+		t.Fatalf("apps = %+v, want %+v", info.Apps, apps)
+	}
+	if !reflect.DeepEqual(info.SkippedCollectors, snapshot.Skipped) {
+		t.Fatalf("skipped = %+v, want %+v", info.SkippedCollectors, snapshot.Skipped)
+	}
+
+	gotPorts, err := s.GetLatestListeningPorts()
+	if err != nil {
+		t.Fatalf("GetLatestListeningPorts: %v", err)
+	}
+	if !reflect.DeepEqual(gotPorts, ports) {
+		t.Fatalf("ports = %+v, want %+v", gotPorts, ports)
+	}
+}
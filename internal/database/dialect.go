@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dialect is the SQL flavour of the database behind a Service. Queries are
+// written once with ? placeholders and double-quoted identifiers where a
+// column name is a reserved word, and rebound for the dialect when run.
+type dialect int
+
+const (
+	dialectMySQL dialect = iota
+	dialectSQLite
+	dialectPostgres
+)
+
+// rebind rewrites ? placeholders to $1, $2, ... for Postgres and
+// double-quoted identifiers to backticks for MySQL. String literals are left
+// untouched.
+func (d dialect) rebind(query string) string {
+	if d == dialectSQLite {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	inLiteral := false
+	param := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inLiteral = !inLiteral
+		case inLiteral:
+		case c == '?' && d == dialectPostgres:
+			param++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(param))
+			continue
+		case c == '"' && d == dialectMySQL:
+			c = '`'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// ignoreDuplicates turns an INSERT ... VALUES statement into one that skips
+// rows violating a unique index instead of failing.
+func (d dialect) ignoreDuplicates(insert string) string {
+	if d == dialectMySQL {
+		return strings.Replace(insert, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+	return insert + " ON CONFLICT DO NOTHING"
+}
+
+// forShare is appended to a SELECT that must see rows other transactions
+// committed after this one started, which MySQL's repeatable read hides from
+// plain reads. SQLite serialises writers, so it needs nothing.
+func (d dialect) forShare() string {
+	if d == dialectSQLite {
+		return ""
+	}
+	return " FOR SHARE"
+}
+
+// dialectDB and dialectTx rebind every query before handing it to
+// database/sql, so the rest of the package can ignore the dialect.
+type dialectDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+type dialectTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (db *dialectDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.rebind(query), args...)
+}
+
+func (db *dialectDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.rebind(query), args...)
+}
+
+func (db *dialectDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.dialect.rebind(query), args...)
+}
+
+func (db *dialectDB) Begin() (*dialectTx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &dialectTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+func (tx *dialectTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.rebind(query), args...)
+}
+
+func (tx *dialectTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.rebind(query), args...)
+}
+
+func (tx *dialectTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.rebind(query), args...)
+}
+
+// insert runs an INSERT into a table with an id primary key and returns the
+// new row's id. Postgres drivers do not report LastInsertId, so the id is
+// read back with RETURNING there.
+func (tx *dialectTx) insert(query string, args ...interface{}) (int64, error) {
+	if tx.dialect == dialectPostgres {
+		var id int64
+		err := tx.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
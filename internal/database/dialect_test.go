@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	query := `SELECT "key", value FROM labels WHERE id = ? AND note = 'who?' AND "type" = ?`
+
+	tests := []struct {
+		dialect dialect
+		want    string
+	}{
+		{dialectSQLite, query},
+		{dialectPostgres, `SELECT "key", value FROM labels WHERE id = $1 AND note = 'who?' AND "type" = $2`},
+		{dialectMySQL, "SELECT `key`, value FROM labels WHERE id = ? AND note = 'who?' AND `type` = ?"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.rebind(query); got != tt.want {
+			t.Errorf("dialect %d: rebind = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestRebindEscapedQuote(t *testing.T) {
+	query := `SELECT ? FROM t WHERE name = 'it''s ?' AND id = ?`
+
+	want := `SELECT $1 FROM t WHERE name = 'it''s ?' AND id = $2`
+	if got := dialectPostgres.rebind(query); got != want {
+		t.Fatalf("rebind = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeDocker(tx *dialectTx, systemInfoID int64, docker osquery.Docker) error {
+	for _, container := range docker.Containers {
+		labels, err := json.Marshal(container.Labels)
+		if err != nil {
+			return fmt.Errorf("failed to encode labels of container '%s': %w", container.Name, err)
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO docker_containers (
+				system_info_id, container_id, name, image, image_id, command, created, state, status, privileged, labels_json
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, container.ID, container.Name, container.Image, container.ImageID, container.Command,
+			container.Created, container.State, container.Status, container.Privileged, string(labels),
+		); err != nil {
+			return fmt.Errorf("database insert error for container '%s': %w", container.Name, err)
+		}
+
+		for _, port := range container.Ports {
+			if _, err := tx.Exec(
+				`INSERT INTO docker_container_ports (system_info_id, container_id, type, port, host_ip, host_port)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				systemInfoID, container.ID, port.Type, port.Port, port.HostIP, port.HostPort,
+			); err != nil {
+				return fmt.Errorf("database insert error for port %d of container '%s': %w", port.Port, container.Name, err)
+			}
+		}
+	}
+
+	for _, image := range docker.Images {
+		if _, err := tx.Exec(
+			"INSERT INTO docker_images (system_info_id, image_id, created, size_bytes, tags) VALUES (?, ?, ?, ?, ?)",
+			systemInfoID, image.ID, image.Created, image.SizeBytes, strings.Join(image.Tags, ","),
+		); err != nil {
+			return fmt.Errorf("database insert error for image '%s': %w", image.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) GetLatestContainers() ([]osquery.Container, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT container_id, name, image, image_id, command, created, state, status, privileged, labels_json
+		FROM docker_containers
+		WHERE system_info_id = ?
+		ORDER BY name, container_id
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get containers: %w", err)
+	}
+	defer rows.Close()
+
+	containers := []osquery.Container{}
+	byID := map[string]int{}
+	for rows.Next() {
+		var container osquery.Container
+		var labels string
+		if err := rows.Scan(&container.ID, &container.Name, &container.Image, &container.ImageID, &container.Command,
+			&container.Created, &container.State, &container.Status, &container.Privileged, &labels); err != nil {
+			return nil, fmt.Errorf("failed to scan container row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(labels), &container.Labels); err != nil {
+			return nil, fmt.Errorf("failed to decode labels of container '%s': %w", container.Name, err)
+		}
+		container.Ports = []osquery.ContainerPort{}
+
+		byID[container.ID] = len(containers)
+		containers = append(containers, container)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over container rows: %w", err)
+	}
+
+	portRows, err := s.db.Query(`
+		SELECT container_id, type, port, host_ip, host_port
+		FROM docker_container_ports
+		WHERE system_info_id = ?
+		ORDER BY port, type
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container ports: %w", err)
+	}
+	defer portRows.Close()
+
+	for portRows.Next() {
+		var port osquery.ContainerPort
+		if err := portRows.Scan(&port.ContainerID, &port.Type, &port.Port, &port.HostIP, &port.HostPort); err != nil {
+			return nil, fmt.Errorf("failed to scan container port row: %w", err)
+		}
+		if i, ok := byID[port.ContainerID]; ok {
+			containers[i].Ports = append(containers[i].Ports, port)
+		}
+	}
+
+	if err := portRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over container port rows: %w", err)
+	}
+
+	return containers, nil
+}
+
+func (s *Service) GetLatestImages() ([]osquery.DockerImage, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT image_id, created, size_bytes, tags
+		FROM docker_images
+		WHERE system_info_id = ?
+		ORDER BY created DESC, image_id
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []osquery.DockerImage{}
+	for rows.Next() {
+		var image osquery.DockerImage
+		if err := rows.Scan(&image.ID, &image.Created, &image.SizeBytes, &image.RawTags); err != nil {
+			return nil, fmt.Errorf("failed to scan image row: %w", err)
+		}
+		image.Tags = osquery.SplitImageTags(image.RawTags)
+		images = append(images, image)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over image rows: %w", err)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeExtensions(tx *dialectTx, systemInfoID int64, extensions []osquery.Extension) error {
+	for _, extension := range extensions {
+		if _, err := tx.Exec(
+			`INSERT INTO extensions (
+				system_info_id, source, uid, username, browser, name, identifier, version, permissions, enabled, path
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, extension.Source, extension.UID, extension.Username, extension.Browser, extension.Name,
+			extension.Identifier, extension.Version, extension.Permissions, extension.Enabled, extension.Path,
+		); err != nil {
+			return fmt.Errorf("database insert error for %s extension '%s': %w", extension.Source, extension.Identifier, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetLatestExtensions() ([]osquery.Extension, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT source, uid, username, browser, name, identifier, version, permissions, enabled, path
+		FROM extensions
+		WHERE system_info_id = ?
+		ORDER BY username, source, name
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extensions: %w", err)
+	}
+	defer rows.Close()
+
+	extensions := []osquery.Extension{}
+	for rows.Next() {
+		var extension osquery.Extension
+		if err := rows.Scan(&extension.Source, &extension.UID, &extension.Username, &extension.Browser, &extension.Name,
+			&extension.Identifier, &extension.Version, &extension.Permissions, &extension.Enabled, &extension.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan extension row: %w", err)
+		}
+		extensions = append(extensions, extension)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over extension rows: %w", err)
+	}
+
+	return extensions, nil
+}
+
+// FindExtensionInstalls answers "who has extension X" across every host that
+// reports into this database, looking only at each host's latest snapshot.
+// identifier matches exactly and name as a case-insensitive substring; at
+// least one must be set.
+func (s *Service) FindExtensionInstalls(identifier, name string) ([]model.ExtensionInstall, error) {
+	rows, err := s.db.Query(`
+		SELECT si.hostname, si.hardware_uuid, si.collected_at,
+			e.source, e.uid, e.username, e.browser, e.name, e.identifier, e.version, e.permissions, e.enabled, e.path
+		FROM extensions e
+		JOIN system_info si ON si.id = e.system_info_id
+		WHERE si.id IN (SELECT MAX(id) FROM system_info GROUP BY hardware_uuid)
+			AND (? = '' OR e.identifier = ?)
+			AND (? = '' OR LOWER(e.name) LIKE ? ESCAPE '!')
+		ORDER BY si.hostname, e.username, e.source
+	`, identifier, identifier, name, likeContains(strings.ToLower(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find extension installs: %w", err)
+	}
+	defer rows.Close()
+
+	installs := []model.ExtensionInstall{}
+	for rows.Next() {
+		var install model.ExtensionInstall
+		extension := &install.Extension
+		if err := rows.Scan(&install.Hostname, &install.HardwareUUID, &install.CollectedAt,
+			&extension.Source, &extension.UID, &extension.Username, &extension.Browser, &extension.Name,
+			&extension.Identifier, &extension.Version, &extension.Permissions, &extension.Enabled, &extension.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan extension install row: %w", err)
+		}
+		installs = append(installs, install)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over extension install rows: %w", err)
+	}
+
+	return installs, nil
+}
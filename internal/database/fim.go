@@ -0,0 +1,226 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+// storeFileIntegrity stores the watched files of a snapshot and the changes
+// since the previous snapshot. Changes are only computed when both snapshots
+// watched the same patterns and collected all of them, otherwise every file
+// under a new or failed pattern would show up as created or deleted.
+func storeFileIntegrity(tx *dialectTx, systemInfoID int64, snapshot osquery.Snapshot) error {
+	for _, pattern := range snapshot.FilePatterns {
+		if _, err := tx.Exec(
+			"INSERT INTO watched_paths (system_info_id, pattern) VALUES (?, ?)",
+			systemInfoID, pattern,
+		); err != nil {
+			return fmt.Errorf("database insert error for watched path '%s': %w", pattern, err)
+		}
+	}
+
+	for _, file := range snapshot.Files {
+		if _, err := tx.Exec(
+			`INSERT INTO watched_files (system_info_id, path, sha256, size, mode, uid, gid, mtime)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, file.Path, file.SHA256, file.Size, file.Mode, file.UID, file.GID, file.Mtime,
+		); err != nil {
+			return fmt.Errorf("database insert error for watched file '%s': %w", file.Path, err)
+		}
+	}
+
+	if len(snapshot.FilePatterns) == 0 || !osquery.FileIntegrityComplete(snapshot.Skipped) {
+		return nil
+	}
+
+	previousID, err := previousComparableFileSnapshot(tx, systemInfoID, snapshot.FilePatterns)
+	if err != nil || previousID == 0 {
+		return err
+	}
+
+	previous, err := getWatchedFiles(tx, previousID)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range osquery.DiffFiles(previous, snapshot.Files) {
+		previousJSON, err := marshalFileEntry(change.Previous)
+		if err != nil {
+			return err
+		}
+		currentJSON, err := marshalFileEntry(change.Current)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO file_changes (system_info_id, path, change_type, previous_json, current_json)
+			VALUES (?, ?, ?, ?, ?)`,
+			systemInfoID, change.Path, change.Type, previousJSON, currentJSON,
+		); err != nil {
+			return fmt.Errorf("database insert error for file change '%s': %w", change.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// previousComparableFileSnapshot returns the same host's snapshot before
+// systemInfoID if it watched exactly the given patterns and collected all of
+// them. Hosts are told apart by hardware_uuid, since several can share one
+// database.
+func previousComparableFileSnapshot(tx *dialectTx, systemInfoID int64, patterns []string) (int64, error) {
+	var previousID int64
+	err := tx.QueryRow(`
+		SELECT id FROM system_info
+		WHERE hardware_uuid = (SELECT hardware_uuid FROM system_info WHERE id = ?)
+			AND id < ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, systemInfoID, systemInfoID).Scan(&previousID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get previous snapshot: %w", err)
+	}
+
+	var skipped int
+	if err := tx.QueryRow(
+		"SELECT COUNT(*) FROM skipped_collectors WHERE system_info_id = ? AND collector LIKE 'fim/%'",
+		previousID,
+	).Scan(&skipped); err != nil {
+		return 0, fmt.Errorf("failed to check skipped file integrity collectors: %w", err)
+	}
+	if skipped > 0 {
+		return 0, nil
+	}
+
+	rows, err := tx.Query("SELECT pattern FROM watched_paths WHERE system_info_id = ?", previousID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get previous watched paths: %w", err)
+	}
+	defer rows.Close()
+
+	var previousPatterns []string
+	for rows.Next() {
+		var pattern string
+		if err := rows.Scan(&pattern); err != nil {
+			return 0, fmt.Errorf("failed to scan watched path row: %w", err)
+		}
+		previousPatterns = append(previousPatterns, pattern)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over watched path rows: %w", err)
+	}
+
+	if !samePatterns(previousPatterns, patterns) {
+		return 0, nil
+	}
+	return previousID, nil
+}
+
+func samePatterns(a, b []string) bool {
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
+func getWatchedFiles(tx *dialectTx, systemInfoID int64) ([]osquery.FileEntry, error) {
+	rows, err := tx.Query(`
+		SELECT path, sha256, size, mode, uid, gid, mtime
+		FROM watched_files
+		WHERE system_info_id = ?
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watched files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []osquery.FileEntry
+	for rows.Next() {
+		var file osquery.FileEntry
+		if err := rows.Scan(&file.Path, &file.SHA256, &file.Size, &file.Mode, &file.UID, &file.GID, &file.Mtime); err != nil {
+			return nil, fmt.Errorf("failed to scan watched file row: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over watched file rows: %w", err)
+	}
+	return files, nil
+}
+
+func marshalFileEntry(file *osquery.FileEntry) (sql.NullString, error) {
+	if file == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode file entry: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// GetFileChanges pages through recorded file changes in the order they were
+// detected. path filters by prefix and changeType by created, modified or
+// deleted; empty values match everything.
+func (s *Service) GetFileChanges(path, changeType string, sinceID int64, limit int) ([]model.FileChangeRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT fc.id, fc.system_info_id, si.collected_at, fc.path, fc.change_type, fc.previous_json, fc.current_json
+		FROM file_changes fc
+		JOIN system_info si ON si.id = fc.system_info_id
+		WHERE (? = '' OR fc.path LIKE ? ESCAPE '!') AND (? = '' OR fc.change_type = ?) AND fc.id > ?
+		ORDER BY fc.id
+		LIMIT ?
+	`, path, likePrefix(path), changeType, changeType, sinceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []model.FileChangeRecord{}
+	for rows.Next() {
+		var record model.FileChangeRecord
+		var previousJSON, currentJSON sql.NullString
+		if err := rows.Scan(&record.ID, &record.SystemInfoID, &record.DetectedAt, &record.Path, &record.Type,
+			&previousJSON, &currentJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan file change row: %w", err)
+		}
+
+		if record.Previous, err = unmarshalFileEntry(previousJSON); err != nil {
+			return nil, err
+		}
+		if record.Current, err = unmarshalFileEntry(currentJSON); err != nil {
+			return nil, err
+		}
+		changes = append(changes, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over file change rows: %w", err)
+	}
+
+	return changes, nil
+}
+
+func unmarshalFileEntry(data sql.NullString) (*osquery.FileEntry, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var file osquery.FileEntry
+	if err := json.Unmarshal([]byte(data.String), &file); err != nil {
+		return nil, fmt.Errorf("failed to decode file entry: %w", err)
+	}
+	return &file, nil
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeKernel(tx *dialectTx, systemInfoID int64, modules []osquery.KernelModule, controls []osquery.SystemControl) error {
+	for _, module := range modules {
+		if _, err := tx.Exec(
+			"INSERT INTO kernel_modules (system_info_id, name, size, used_by, status, address) VALUES (?, ?, ?, ?, ?, ?)",
+			systemInfoID, module.Name, module.Size, module.UsedBy, module.Status, module.Address,
+		); err != nil {
+			return fmt.Errorf("database insert error for kernel module '%s': %w", module.Name, err)
+		}
+	}
+
+	for _, control := range controls {
+		if _, err := tx.Exec(
+			`INSERT INTO system_controls (system_info_id, name, current_value, config_value, subsystem, type)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			systemInfoID, control.Name, control.CurrentValue, control.ConfigValue, control.Subsystem, control.Type,
+		); err != nil {
+			return fmt.Errorf("database insert error for system control '%s': %w", control.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) GetLatestKernelModules() ([]osquery.KernelModule, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT name, size, used_by, status, address
+		FROM kernel_modules
+		WHERE system_info_id = ?
+		ORDER BY name
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kernel modules: %w", err)
+	}
+	defer rows.Close()
+
+	modules := []osquery.KernelModule{}
+	for rows.Next() {
+		var module osquery.KernelModule
+		if err := rows.Scan(&module.Name, &module.Size, &module.UsedBy, &module.Status, &module.Address); err != nil {
+			return nil, fmt.Errorf("failed to scan kernel module row: %w", err)
+		}
+		modules = append(modules, module)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over kernel module rows: %w", err)
+	}
+
+	return modules, nil
+}
+
+func (s *Service) GetLatestSystemControls() ([]osquery.SystemControl, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT name, current_value, config_value, subsystem, type
+		FROM system_controls
+		WHERE system_info_id = ?
+		ORDER BY name
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system controls: %w", err)
+	}
+	defer rows.Close()
+
+	controls := []osquery.SystemControl{}
+	for rows.Next() {
+		var control osquery.SystemControl
+		if err := rows.Scan(&control.Name, &control.CurrentValue, &control.ConfigValue, &control.Subsystem, &control.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan system control row: %w", err)
+		}
+		controls = append(controls, control)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over system control rows: %w", err)
+	}
+
+	return controls, nil
+}
+
+// GetHostSystemControls returns the sysctls of every host's latest snapshot,
+// one entry per hardware_uuid. A host whose latest snapshot has no sysctls is
+// still listed, with none, and marked as not collected when the snapshot
+// skipped the collector.
+func (s *Service) GetHostSystemControls() ([]model.HostSystemControls, error) {
+	rows, err := s.db.Query(`
+		SELECT si.id, si.hostname, si.hardware_uuid, si.collected_at,
+			(SELECT COUNT(*) FROM skipped_collectors skc
+				WHERE skc.system_info_id = si.id AND skc.collector = 'kernel/system_controls'),
+			sc.name, sc.current_value, sc.config_value, sc.subsystem, sc.type
+		FROM system_info si
+		LEFT JOIN system_controls sc ON sc.system_info_id = si.id
+		WHERE si.id IN (SELECT MAX(id) FROM system_info GROUP BY hardware_uuid)
+		ORDER BY si.hostname, si.id, sc.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host system controls: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := []model.HostSystemControls{}
+	var lastID int64
+	for rows.Next() {
+		var id int64
+		var host model.HostSystemControls
+		var skipped int
+		var name, currentValue, configValue, subsystem, controlType sql.NullString
+		if err := rows.Scan(&id, &host.Hostname, &host.HardwareUUID, &host.CollectedAt, &skipped,
+			&name, &currentValue, &configValue, &subsystem, &controlType); err != nil {
+			return nil, fmt.Errorf("failed to scan host system control row: %w", err)
+		}
+
+		if len(hosts) == 0 || id != lastID {
+			host.Collected = skipped == 0
+			host.Controls = []osquery.SystemControl{}
+			hosts = append(hosts, host)
+			lastID = id
+		}
+		if name.Valid {
+			current := &hosts[len(hosts)-1]
+			current.Controls = append(current.Controls, osquery.SystemControl{
+				Name:         name.String,
+				CurrentValue: currentValue.String,
+				ConfigValue:  configValue.String,
+				Subsystem:    subsystem.String,
+				Type:         controlType.String,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over host system control rows: %w", err)
+	}
+
+	return hosts, nil
+}
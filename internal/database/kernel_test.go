@@ -0,0 +1,32 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func TestGetHostSystemControlsNotCollected(t *testing.T) {
+	s := newTestService(t)
+
+	collected := testSnapshot("uuid-a", nil, nil)
+	collected.SystemControls = []osquery.SystemControl{{Name: "net.ipv4.ip_forward", CurrentValue: "0"}}
+	storeSnapshot(t, s, collected)
+
+	skipped := testSnapshot("uuid-b", nil, nil)
+	skipped.Skipped = []osquery.SkippedCollector{{Name: "kernel/system_controls", Reason: "table system_controls is not available"}}
+	storeSnapshot(t, s, skipped)
+
+	hosts, err := s.GetHostSystemControls()
+	if err != nil {
+		t.Fatalf("GetHostSystemControls: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, host := range hosts {
+		got[host.HardwareUUID] = host.Collected
+	}
+	if want := map[string]bool{"uuid-a": true, "uuid-b": false}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("collected = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,426 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// migrationsFS holds one directory of migrations per dialect. Each migration
+// is a pair of files named <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+const (
+	migrationLockName    = "osquery_mvp_schema_migrations"
+	migrationLockKey     = 7262150393
+	migrationLockTimeout = 5 * time.Minute
+
+	// baselineVersion is the migration holding the schema that init.sql used
+	// to create. Databases created that way are marked as already at it.
+	baselineVersion = 1
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+func (d dialect) migrationsDir() string {
+	switch d {
+	case dialectPostgres:
+		return "migrations/postgres"
+	case dialectSQLite:
+		return "migrations/sqlite"
+	default:
+		return "migrations/mysql"
+	}
+}
+
+func loadMigrations(d dialect) ([]Migration, error) {
+	dir := d.migrationsDir()
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", name)
+		}
+
+		data, err := fs.ReadFile(migrationsFS, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", name, err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		} else if migration.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, label)
+		}
+		if direction == "up" {
+			migration.up = string(data)
+		} else {
+			migration.down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration in order.
+func (s *Service) MigrateUp(ctx context.Context) error {
+	migrations, err := loadMigrations(s.db.dialect)
+	if err != nil {
+		return err
+	}
+
+	return s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		if err := s.baseline(ctx, conn, migrations); err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if err := s.applyMigration(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the given number of most recently applied migrations.
+func (s *Service) MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := loadMigrations(s.db.dialect)
+	if err != nil {
+		return err
+	}
+
+	return s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := s.appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			if _, ok := applied[migrations[i].Version]; !ok {
+				continue
+			}
+			if err := s.applyMigration(ctx, conn, migrations[i], false); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// MigrationStatus lists every known migration and whether it is applied.
+func (s *Service) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(s.db.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := s.createMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withMigrationLock runs fn on a single connection holding a database-wide
+// lock, so replicas starting together migrate one at a time. SQLite needs no
+// separate lock: each migration's transaction takes the database write lock
+// and re-checks whether it is still pending.
+func (s *Service) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	switch s.db.dialect {
+	case dialectMySQL:
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)",
+			migrationLockName, int(migrationLockTimeout.Seconds())).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired.Int64 != 1 {
+			return fmt.Errorf("timed out waiting for migration lock after %s", migrationLockTimeout)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName)
+
+	case dialectPostgres:
+		lockCtx, cancel := context.WithTimeout(ctx, migrationLockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+
+	if err := s.createMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (s *Service) createMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	appliedAt := "TIMESTAMP DEFAULT CURRENT_TIMESTAMP"
+	if s.db.dialect == dialectPostgres {
+		appliedAt = "TIMESTAMPTZ NOT NULL DEFAULT now()"
+	}
+
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at `+appliedAt+`
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, timestamp{&appliedAt}); err != nil {
+			return nil, fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over migration rows: %w", err)
+	}
+
+	return applied, nil
+}
+
+// baseline marks the initial migration as applied on databases whose schema
+// was created by init.sql before migrations existed. Migration 1 is exactly
+// that schema, so every later change is still applied on top of it.
+func (s *Service) baseline(ctx context.Context, conn *sql.Conn, migrations []Migration) error {
+	var count int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count applied migrations: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	exists, err := s.tableExists(ctx, conn, "system_info")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	for _, migration := range migrations {
+		if migration.Version != baselineVersion {
+			continue
+		}
+		logger.Log.Info("Existing schema found, marking initial migration as applied",
+			zap.Int64("version", migration.Version),
+			zap.String("name", migration.Name))
+		if _, err := conn.ExecContext(ctx, s.db.dialect.rebind(
+			"INSERT INTO schema_migrations (version, name) VALUES (?, ?)"),
+			migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("failed to record baseline migration: %w", err)
+		}
+	}
+	return nil
+}
+
+// tableExists asks the catalog whether the named table exists in the
+// current database.
+func (s *Service) tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	switch s.db.dialect {
+	case dialectPostgres:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	case dialectSQLite:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	}
+
+	var count int
+	if err := conn.QueryRowContext(ctx, s.db.dialect.rebind(query), table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// applyMigration runs one migration up or down in a transaction, skipping it
+// if another replica got there first. MySQL commits DDL implicitly, so a
+// migration that fails half way there has to be repaired by hand.
+func (s *Service) applyMigration(ctx context.Context, conn *sql.Conn, migration Migration, up bool) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var count int
+	if err = tx.QueryRowContext(ctx, s.db.dialect.rebind(
+		"SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), migration.Version).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check migration %d: %w", migration.Version, err)
+	}
+	if (count > 0) == up {
+		return tx.Rollback()
+	}
+
+	script, direction := migration.up, "up"
+	if !up {
+		script, direction = migration.down, "down"
+	}
+	log := logger.Log.With(
+		zap.Int64("version", migration.Version),
+		zap.String("name", migration.Name),
+		zap.String("direction", direction))
+	log.Info("Applying migration")
+
+	for _, statement := range splitStatements(script) {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			log.Error("Migration failed",
+				zap.Error(err))
+			return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, s.db.dialect.rebind(
+			"INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, s.db.dialect.rebind(
+			"DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// splitStatements splits a migration script on semicolons outside string
+// literals and drops -- comments, since not every driver runs several
+// statements in one Exec.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	inLiteral := false
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'':
+			inLiteral = !inLiteral
+		case !inLiteral && c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+			continue
+		case !inLiteral && c == ';':
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
@@ -0,0 +1,53 @@
+DROP TABLE IF EXISTS query_events;
+DROP TABLE IF EXISTS pack_results;
+DROP TABLE IF EXISTS skipped_collectors;
+DROP TABLE IF EXISTS extensions;
+DROP TABLE IF EXISTS docker_images;
+DROP TABLE IF EXISTS docker_container_ports;
+DROP TABLE IF EXISTS docker_containers;
+DROP TABLE IF EXISTS system_controls;
+DROP TABLE IF EXISTS kernel_modules;
+DROP TABLE IF EXISTS mounts;
+DROP TABLE IF EXISTS file_changes;
+DROP TABLE IF EXISTS watched_files;
+DROP TABLE IF EXISTS watched_paths;
+DROP TABLE IF EXISTS certificates;
+DROP TABLE IF EXISTS startup_items;
+DROP TABLE IF EXISTS crontab;
+DROP TABLE IF EXISTS systemd_units;
+DROP TABLE IF EXISTS processes;
+DROP TABLE IF EXISTS sudo_accounts;
+DROP TABLE IF EXISTS sudoers_rules;
+DROP TABLE IF EXISTS authorized_keys;
+DROP TABLE IF EXISTS logged_in_users;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS local_groups;
+DROP TABLE IF EXISTS local_users;
+DROP TABLE IF EXISTS listening_ports;
+
+DROP INDEX idx_system_info_hardware_uuid ON system_info;
+ALTER TABLE installed_apps
+    DROP COLUMN source,
+    DROP COLUMN arch,
+    DROP COLUMN vendor,
+    DROP COLUMN install_time;
+
+ALTER TABLE system_info
+    DROP COLUMN os_build,
+    DROP COLUMN os_major,
+    DROP COLUMN os_minor,
+    DROP COLUMN os_arch,
+    DROP COLUMN hostname,
+    DROP COLUMN computer_name,
+    DROP COLUMN hardware_uuid,
+    DROP COLUMN cpu_brand,
+    DROP COLUMN cpu_physical_cores,
+    DROP COLUMN cpu_logical_cores,
+    DROP COLUMN physical_memory,
+    DROP COLUMN hardware_vendor,
+    DROP COLUMN hardware_model,
+    DROP COLUMN hardware_serial,
+    DROP COLUMN kernel_version,
+    DROP COLUMN uptime_seconds,
+    DROP COLUMN kernel_arguments,
+    DROP COLUMN kernel_path;
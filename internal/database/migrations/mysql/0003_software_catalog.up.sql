@@ -0,0 +1,68 @@
+-- Installed apps move from one row per app per snapshot to a catalog of
+-- distinct software and deduplicated software sets. A snapshot points at the
+-- set matching the sha256 of its app list, so an unchanged list only bumps
+-- the set's last_seen.
+
+CREATE TABLE software (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) COLLATE utf8mb4_bin NOT NULL,
+    version VARCHAR(255) COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+    source VARCHAR(32) COLLATE utf8mb4_bin NOT NULL DEFAULT ''
+);
+
+
+CREATE TABLE software_sets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    content_hash CHAR(64) NOT NULL,
+    app_count INT NOT NULL DEFAULT 0,
+    first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+
+CREATE TABLE software_set_members (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    software_set_id INT NOT NULL,
+    software_id INT NOT NULL,
+    arch VARCHAR(64) NOT NULL DEFAULT '',
+    vendor VARCHAR(255) NOT NULL DEFAULT '',
+    install_time BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (software_set_id) REFERENCES software_sets(id) ON DELETE CASCADE,
+    FOREIGN KEY (software_id) REFERENCES software(id)
+);
+
+
+ALTER TABLE system_info ADD COLUMN software_set_id INT;
+
+
+CREATE UNIQUE INDEX idx_software_name_version_source ON software(name, version, source);
+CREATE UNIQUE INDEX idx_software_sets_content_hash ON software_sets(content_hash);
+CREATE INDEX idx_software_set_members_software_set_id ON software_set_members(software_set_id);
+CREATE INDEX idx_software_set_members_software_id ON software_set_members(software_id);
+CREATE INDEX idx_system_info_software_set_id ON system_info(software_set_id);
+
+
+-- Existing snapshots each get their own set, keyed by a placeholder hash that
+-- no new app list can match.
+INSERT INTO software (name, version, source)
+SELECT DISTINCT name COLLATE utf8mb4_bin, COALESCE(version, '') COLLATE utf8mb4_bin, source COLLATE utf8mb4_bin
+FROM installed_apps;
+
+INSERT INTO software_sets (content_hash, app_count, first_seen, last_seen)
+SELECT CONCAT('legacy:', si.id), COUNT(*), si.collected_at, si.collected_at
+FROM system_info si
+JOIN installed_apps ia ON ia.system_info_id = si.id
+GROUP BY si.id, si.collected_at;
+
+UPDATE system_info
+SET software_set_id = (SELECT id FROM software_sets WHERE content_hash = CONCAT('legacy:', system_info.id));
+
+INSERT INTO software_set_members (software_set_id, software_id, arch, vendor, install_time)
+SELECT si.software_set_id, s.id, ia.arch, ia.vendor, ia.install_time
+FROM installed_apps ia
+JOIN system_info si ON si.id = ia.system_info_id
+JOIN software s ON s.name = ia.name COLLATE utf8mb4_bin
+    AND s.version = COALESCE(ia.version, '') COLLATE utf8mb4_bin
+    AND s.source = ia.source COLLATE utf8mb4_bin;
+
+DROP TABLE installed_apps;
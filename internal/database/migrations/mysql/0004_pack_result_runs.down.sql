@@ -0,0 +1,4 @@
+DROP INDEX idx_pack_results_run ON pack_results;
+CREATE INDEX idx_pack_results_query ON pack_results(pack_name, query_name, id);
+ALTER TABLE pack_results DROP COLUMN run_number;
+ALTER TABLE pack_results DROP COLUMN host_identifier;
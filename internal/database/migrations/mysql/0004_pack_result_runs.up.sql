@@ -0,0 +1,21 @@
+-- Scheduled pack results are keyed by host, pack, query and run, so results
+-- from several hosts sharing a database no longer diff against each other.
+-- Rows stored before hosts were recorded keep an empty host_identifier.
+
+ALTER TABLE pack_results ADD COLUMN host_identifier VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE pack_results ADD COLUMN run_number BIGINT NOT NULL DEFAULT 1;
+
+UPDATE pack_results pr
+JOIN (
+    SELECT later.id, COUNT(*) AS run_number
+    FROM pack_results later
+    JOIN pack_results runs
+        ON runs.pack_name = later.pack_name
+        AND runs.query_name = later.query_name
+        AND runs.id <= later.id
+    GROUP BY later.id
+) numbered ON numbered.id = pr.id
+SET pr.run_number = numbered.run_number;
+
+DROP INDEX idx_pack_results_query ON pack_results;
+CREATE UNIQUE INDEX idx_pack_results_run ON pack_results(host_identifier, pack_name, query_name, run_number);
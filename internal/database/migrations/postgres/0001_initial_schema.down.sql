@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS installed_apps;
+DROP TABLE IF EXISTS system_info;
@@ -0,0 +1,397 @@
+-- Host identity columns and the per-collector inventory tables added
+-- alongside the collectors, on top of the schema init.sql used to create.
+
+ALTER TABLE system_info
+    ADD COLUMN os_build VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN os_major INT NOT NULL DEFAULT 0,
+    ADD COLUMN os_minor INT NOT NULL DEFAULT 0,
+    ADD COLUMN os_arch VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN hostname VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN computer_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN hardware_uuid VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN cpu_brand VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN cpu_physical_cores INT NOT NULL DEFAULT 0,
+    ADD COLUMN cpu_logical_cores INT NOT NULL DEFAULT 0,
+    ADD COLUMN physical_memory BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN hardware_vendor VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN hardware_model VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN hardware_serial VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN kernel_version VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN uptime_seconds BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN kernel_arguments VARCHAR(4096) NOT NULL DEFAULT '',
+    ADD COLUMN kernel_path VARCHAR(1024) NOT NULL DEFAULT '';
+
+ALTER TABLE installed_apps
+    ADD COLUMN source VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN arch VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN vendor VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN install_time BIGINT NOT NULL DEFAULT 0;
+
+
+CREATE TABLE IF NOT EXISTS listening_ports (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    pid BIGINT NOT NULL DEFAULT 0,
+    port INT NOT NULL,
+    protocol VARCHAR(16) NOT NULL,
+    family INT NOT NULL DEFAULT 0,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    interface_name VARCHAR(255) NOT NULL DEFAULT '',
+    interface_mac VARCHAR(64) NOT NULL DEFAULT '',
+    process_name VARCHAR(255) NOT NULL DEFAULT '',
+    process_path TEXT,
+    uid BIGINT NOT NULL DEFAULT 0,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS local_users (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    uid BIGINT NOT NULL,
+    gid BIGINT NOT NULL DEFAULT 0,
+    username VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    directory VARCHAR(1024) NOT NULL DEFAULT '',
+    shell VARCHAR(1024) NOT NULL DEFAULT '',
+    uuid VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS local_groups (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    gid BIGINT NOT NULL,
+    groupname VARCHAR(255) NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    uid BIGINT NOT NULL,
+    gid BIGINT NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS logged_in_users (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    type VARCHAR(64) NOT NULL DEFAULT '',
+    "user" VARCHAR(255) NOT NULL DEFAULT '',
+    tty VARCHAR(255) NOT NULL DEFAULT '',
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    login_time BIGINT NOT NULL DEFAULT 0,
+    pid BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS authorized_keys (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    uid BIGINT NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    algorithm VARCHAR(64) NOT NULL DEFAULT '',
+    key_data TEXT NOT NULL,
+    key_file VARCHAR(1024) NOT NULL DEFAULT '',
+    comment VARCHAR(1024) NOT NULL DEFAULT '',
+    fingerprint CHAR(64) NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS sudoers_rules (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    source VARCHAR(1024) NOT NULL DEFAULT '',
+    header VARCHAR(1024) NOT NULL DEFAULT '',
+    rule_details TEXT NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS sudo_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    username VARCHAR(255) NOT NULL,
+    uid BIGINT NOT NULL,
+    via VARCHAR(255) NOT NULL,
+    source VARCHAR(1024) NOT NULL DEFAULT '',
+    rule VARCHAR(1024) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS processes (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    pid BIGINT NOT NULL,
+    parent BIGINT NOT NULL DEFAULT 0,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    cmdline TEXT NOT NULL,
+    uid BIGINT NOT NULL DEFAULT 0,
+    start_time BIGINT NOT NULL DEFAULT 0,
+    resident_size BIGINT NOT NULL DEFAULT 0,
+    user_time BIGINT NOT NULL DEFAULT 0,
+    system_time BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS systemd_units (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    unit_id VARCHAR(255) NOT NULL,
+    description VARCHAR(1024) NOT NULL DEFAULT '',
+    load_state VARCHAR(64) NOT NULL DEFAULT '',
+    active_state VARCHAR(64) NOT NULL DEFAULT '',
+    sub_state VARCHAR(64) NOT NULL DEFAULT '',
+    fragment_path VARCHAR(1024) NOT NULL DEFAULT '',
+    source_path VARCHAR(1024) NOT NULL DEFAULT '',
+    "user" VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS crontab (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    event VARCHAR(64) NOT NULL DEFAULT '',
+    minute VARCHAR(64) NOT NULL DEFAULT '',
+    hour VARCHAR(64) NOT NULL DEFAULT '',
+    day_of_month VARCHAR(64) NOT NULL DEFAULT '',
+    month VARCHAR(64) NOT NULL DEFAULT '',
+    day_of_week VARCHAR(64) NOT NULL DEFAULT '',
+    command TEXT NOT NULL,
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS startup_items (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    args TEXT NOT NULL,
+    type VARCHAR(64) NOT NULL DEFAULT '',
+    source VARCHAR(1024) NOT NULL DEFAULT '',
+    status VARCHAR(64) NOT NULL DEFAULT '',
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS certificates (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    common_name VARCHAR(1024) NOT NULL DEFAULT '',
+    subject TEXT NOT NULL,
+    issuer TEXT NOT NULL,
+    serial VARCHAR(255) NOT NULL DEFAULT '',
+    sha1 CHAR(40) NOT NULL,
+    not_valid_before BIGINT NOT NULL DEFAULT 0,
+    not_valid_after BIGINT NOT NULL,
+    ca BOOLEAN NOT NULL DEFAULT FALSE,
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS watched_paths (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    pattern VARCHAR(1024) NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS watched_files (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    path VARCHAR(1024) NOT NULL,
+    sha256 CHAR(64) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    mode VARCHAR(16) NOT NULL DEFAULT '',
+    uid BIGINT NOT NULL DEFAULT 0,
+    gid BIGINT NOT NULL DEFAULT 0,
+    mtime BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS file_changes (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    path VARCHAR(1024) NOT NULL,
+    change_type VARCHAR(16) NOT NULL CHECK (change_type IN ('created', 'modified', 'deleted')),
+    previous_json JSONB,
+    current_json JSONB,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS mounts (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    device VARCHAR(1024) NOT NULL DEFAULT '',
+    path VARCHAR(1024) NOT NULL,
+    type VARCHAR(64) NOT NULL DEFAULT '',
+    blocks_size BIGINT NOT NULL DEFAULT 0,
+    blocks BIGINT NOT NULL DEFAULT 0,
+    blocks_free BIGINT NOT NULL DEFAULT 0,
+    blocks_available BIGINT NOT NULL DEFAULT 0,
+    inodes BIGINT NOT NULL DEFAULT 0,
+    inodes_free BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS kernel_modules (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    name VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    used_by VARCHAR(1024) NOT NULL DEFAULT '',
+    status VARCHAR(64) NOT NULL DEFAULT '',
+    address VARCHAR(64) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS system_controls (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    name VARCHAR(255) NOT NULL,
+    current_value VARCHAR(1024) NOT NULL DEFAULT '',
+    config_value VARCHAR(1024) NOT NULL DEFAULT '',
+    subsystem VARCHAR(64) NOT NULL DEFAULT '',
+    type VARCHAR(64) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS docker_containers (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    container_id VARCHAR(128) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    image VARCHAR(1024) NOT NULL,
+    image_id VARCHAR(128) NOT NULL DEFAULT '',
+    command TEXT NOT NULL,
+    created BIGINT NOT NULL DEFAULT 0,
+    state VARCHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(255) NOT NULL DEFAULT '',
+    privileged BOOLEAN NOT NULL DEFAULT FALSE,
+    labels_json JSONB NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS docker_container_ports (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    container_id VARCHAR(128) NOT NULL,
+    type VARCHAR(16) NOT NULL DEFAULT '',
+    port INT NOT NULL,
+    host_ip VARCHAR(64) NOT NULL DEFAULT '',
+    host_port INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS docker_images (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    image_id VARCHAR(128) NOT NULL,
+    created BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    tags VARCHAR(4096) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS extensions (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    source VARCHAR(32) NOT NULL,
+    uid BIGINT NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    browser VARCHAR(64) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    identifier VARCHAR(255) NOT NULL,
+    version VARCHAR(64) NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS skipped_collectors (
+    id BIGSERIAL PRIMARY KEY,
+    system_info_id BIGINT,
+    collector VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS pack_results (
+    id BIGSERIAL PRIMARY KEY,
+    pack_name VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    snapshot BOOLEAN NOT NULL DEFAULT FALSE,
+    row_count INT NOT NULL DEFAULT 0,
+    rows_json JSONB NOT NULL,
+    collected_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+CREATE TABLE IF NOT EXISTS query_events (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    host_identifier VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    unix_time BIGINT NOT NULL,
+    epoch BIGINT NOT NULL DEFAULT 0,
+    counter BIGINT NOT NULL DEFAULT 0,
+    columns_json JSONB NOT NULL
+);
+
+
+CREATE INDEX IF NOT EXISTS idx_skipped_collectors_system_info_id ON skipped_collectors(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_pack_results_query ON pack_results(pack_name, query_name, id);
+CREATE INDEX IF NOT EXISTS idx_query_events_name ON query_events(name, id);
+CREATE INDEX IF NOT EXISTS idx_system_info_hardware_uuid ON system_info(hardware_uuid);
+CREATE INDEX IF NOT EXISTS idx_listening_ports_system_info_id ON listening_ports(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_local_users_system_info_id ON local_users(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_local_groups_system_info_id ON local_groups(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_user_groups_system_info_id ON user_groups(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_logged_in_users_system_info_id ON logged_in_users(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_authorized_keys_system_info_id ON authorized_keys(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_sudoers_rules_system_info_id ON sudoers_rules(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_sudo_accounts_system_info_id ON sudo_accounts(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_processes_system_info_id ON processes(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_systemd_units_system_info_id ON systemd_units(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_crontab_system_info_id ON crontab(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_startup_items_system_info_id ON startup_items(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_certificates_system_info_id_not_valid_after ON certificates(system_info_id, not_valid_after);
+CREATE INDEX IF NOT EXISTS idx_watched_paths_system_info_id ON watched_paths(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_watched_files_system_info_id ON watched_files(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_file_changes_path ON file_changes(path);
+CREATE INDEX IF NOT EXISTS idx_mounts_system_info_id ON mounts(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_mounts_path ON mounts(path);
+CREATE INDEX IF NOT EXISTS idx_kernel_modules_system_info_id ON kernel_modules(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_system_controls_system_info_id ON system_controls(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_docker_containers_system_info_id ON docker_containers(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_docker_container_ports_system_info_id ON docker_container_ports(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_docker_images_system_info_id ON docker_images(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_extensions_system_info_id ON extensions(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_extensions_identifier ON extensions(identifier);
@@ -0,0 +1,17 @@
+-- Scheduled pack results are keyed by host, pack, query and run, so results
+-- from several hosts sharing a database no longer diff against each other.
+-- Rows stored before hosts were recorded keep an empty host_identifier.
+
+ALTER TABLE pack_results ADD COLUMN host_identifier VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE pack_results ADD COLUMN run_number BIGINT NOT NULL DEFAULT 1;
+
+UPDATE pack_results
+SET run_number = runs.run_number
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY pack_name, query_name ORDER BY id) AS run_number
+    FROM pack_results
+) runs
+WHERE runs.id = pack_results.id;
+
+DROP INDEX IF EXISTS idx_pack_results_query;
+CREATE UNIQUE INDEX idx_pack_results_run ON pack_results(host_identifier, pack_name, query_name, run_number);
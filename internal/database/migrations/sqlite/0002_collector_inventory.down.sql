@@ -0,0 +1,51 @@
+DROP TABLE IF EXISTS query_events;
+DROP TABLE IF EXISTS pack_results;
+DROP TABLE IF EXISTS skipped_collectors;
+DROP TABLE IF EXISTS extensions;
+DROP TABLE IF EXISTS docker_images;
+DROP TABLE IF EXISTS docker_container_ports;
+DROP TABLE IF EXISTS docker_containers;
+DROP TABLE IF EXISTS system_controls;
+DROP TABLE IF EXISTS kernel_modules;
+DROP TABLE IF EXISTS mounts;
+DROP TABLE IF EXISTS file_changes;
+DROP TABLE IF EXISTS watched_files;
+DROP TABLE IF EXISTS watched_paths;
+DROP TABLE IF EXISTS certificates;
+DROP TABLE IF EXISTS startup_items;
+DROP TABLE IF EXISTS crontab;
+DROP TABLE IF EXISTS systemd_units;
+DROP TABLE IF EXISTS processes;
+DROP TABLE IF EXISTS sudo_accounts;
+DROP TABLE IF EXISTS sudoers_rules;
+DROP TABLE IF EXISTS authorized_keys;
+DROP TABLE IF EXISTS logged_in_users;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS local_groups;
+DROP TABLE IF EXISTS local_users;
+DROP TABLE IF EXISTS listening_ports;
+
+DROP INDEX IF EXISTS idx_system_info_hardware_uuid;
+ALTER TABLE installed_apps DROP COLUMN source;
+ALTER TABLE installed_apps DROP COLUMN arch;
+ALTER TABLE installed_apps DROP COLUMN vendor;
+ALTER TABLE installed_apps DROP COLUMN install_time;
+
+ALTER TABLE system_info DROP COLUMN os_build;
+ALTER TABLE system_info DROP COLUMN os_major;
+ALTER TABLE system_info DROP COLUMN os_minor;
+ALTER TABLE system_info DROP COLUMN os_arch;
+ALTER TABLE system_info DROP COLUMN hostname;
+ALTER TABLE system_info DROP COLUMN computer_name;
+ALTER TABLE system_info DROP COLUMN hardware_uuid;
+ALTER TABLE system_info DROP COLUMN cpu_brand;
+ALTER TABLE system_info DROP COLUMN cpu_physical_cores;
+ALTER TABLE system_info DROP COLUMN cpu_logical_cores;
+ALTER TABLE system_info DROP COLUMN physical_memory;
+ALTER TABLE system_info DROP COLUMN hardware_vendor;
+ALTER TABLE system_info DROP COLUMN hardware_model;
+ALTER TABLE system_info DROP COLUMN hardware_serial;
+ALTER TABLE system_info DROP COLUMN kernel_version;
+ALTER TABLE system_info DROP COLUMN uptime_seconds;
+ALTER TABLE system_info DROP COLUMN kernel_arguments;
+ALTER TABLE system_info DROP COLUMN kernel_path;
@@ -0,0 +1,25 @@
+CREATE TABLE installed_apps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    system_info_id INTEGER,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255),
+    source VARCHAR(32) NOT NULL DEFAULT '',
+    arch VARCHAR(64) NOT NULL DEFAULT '',
+    vendor VARCHAR(255) NOT NULL DEFAULT '',
+    install_time BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_installed_apps_system_info_id ON installed_apps(system_info_id);
+
+INSERT INTO installed_apps (system_info_id, name, version, source, arch, vendor, install_time)
+SELECT si.id, s.name, s.version, s.source, m.arch, m.vendor, m.install_time
+FROM system_info si
+JOIN software_set_members m ON m.software_set_id = si.software_set_id
+JOIN software s ON s.id = m.software_id;
+
+DROP INDEX IF EXISTS idx_system_info_software_set_id;
+ALTER TABLE system_info DROP COLUMN software_set_id;
+DROP TABLE software_set_members;
+DROP TABLE software_sets;
+DROP TABLE software;
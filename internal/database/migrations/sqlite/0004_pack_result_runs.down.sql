@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_pack_results_run;
+CREATE INDEX idx_pack_results_query ON pack_results(pack_name, query_name, id);
+ALTER TABLE pack_results DROP COLUMN run_number;
+ALTER TABLE pack_results DROP COLUMN host_identifier;
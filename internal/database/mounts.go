@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeMounts(tx *dialectTx, systemInfoID int64, mounts []osquery.Mount) error {
+	for _, mount := range mounts {
+		_, err := tx.Exec(
+			`INSERT INTO mounts (
+				system_info_id, device, path, type, blocks_size, blocks, blocks_free, blocks_available,
+				inodes, inodes_free
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, mount.Device, mount.Path, mount.Type, mount.BlockSize, mount.Blocks, mount.BlocksFree, mount.BlocksAvailable,
+			mount.Inodes, mount.InodesFree,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for mount '%s': %w", mount.Path, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetLatestMounts() ([]model.MountUsage, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryMounts(`
+		SELECT m.device, m.path, m.type, m.blocks_size, m.blocks, m.blocks_free, m.blocks_available,
+			m.inodes, m.inodes_free, si.collected_at
+		FROM mounts m
+		JOIN system_info si ON si.id = m.system_info_id
+		WHERE m.system_info_id = ?
+		ORDER BY m.path
+	`, systemInfoID)
+}
+
+// GetMountHistory returns the usage of every mount, or of the mount at path
+// when it is non-empty, on the latest snapshot's host (by hardware_uuid) for
+// snapshots collected since the given time.
+func (s *Service) GetMountHistory(path string, since time.Time) ([]model.MountUsage, error) {
+	latestID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryMounts(`
+		SELECT m.device, m.path, m.type, m.blocks_size, m.blocks, m.blocks_free, m.blocks_available,
+			m.inodes, m.inodes_free, si.collected_at
+		FROM mounts m
+		JOIN system_info si ON si.id = m.system_info_id
+		WHERE si.hardware_uuid = (SELECT hardware_uuid FROM system_info WHERE id = ?)
+			AND (? = '' OR m.path = ?) AND si.collected_at >= ?
+		ORDER BY m.path, si.collected_at
+	`, latestID, path, path, since.UTC())
+}
+
+func (s *Service) queryMounts(query string, args ...interface{}) ([]model.MountUsage, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mounts: %w", err)
+	}
+	defer rows.Close()
+
+	mounts := []model.MountUsage{}
+	for rows.Next() {
+		var usage model.MountUsage
+		mount := &usage.Mount
+		if err := rows.Scan(&mount.Device, &mount.Path, &mount.Type, &mount.BlockSize, &mount.Blocks, &mount.BlocksFree, &mount.BlocksAvailable,
+			&mount.Inodes, &mount.InodesFree, &usage.CollectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan mount row: %w", err)
+		}
+
+		usage.UsedPercent = mount.UsedPercent()
+		usage.InodesUsedPercent = mount.InodesUsedPercent()
+		mounts = append(mounts, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over mount rows: %w", err)
+	}
+
+	return mounts, nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func TestGetMountHistoryLatestHost(t *testing.T) {
+	s := newTestService(t)
+
+	for _, host := range []string{"uuid-a", "uuid-b", "uuid-a"} {
+		snapshot := testSnapshot(host, nil, nil)
+		snapshot.Mounts = []osquery.Mount{
+			{Device: "/dev/sda1", Path: "/", Type: "ext4", BlockSize: 4096, Blocks: 100, BlocksFree: 50, BlocksAvailable: 40},
+			{Device: "/dev/" + host, Path: "/data", Type: "xfs", BlockSize: 4096, Blocks: 100, BlocksFree: 90, BlocksAvailable: 90},
+		}
+		storeSnapshot(t, s, snapshot)
+	}
+
+	history, err := s.GetMountHistory("", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetMountHistory: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("history has %d points, want 2 mounts on 2 snapshots of uuid-a", len(history))
+	}
+	for _, point := range history {
+		if point.Path == "/data" && point.Device != "/dev/uuid-a" {
+			t.Errorf("history includes %s from another host", point.Device)
+		}
+	}
+
+	history, err = s.GetMountHistory("/data", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetMountHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("/data history has %d points, want 2", len(history))
+	}
+}
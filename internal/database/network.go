@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeListeningPorts(tx *dialectTx, systemInfoID int64, ports []osquery.ListeningPort) error {
+	for _, port := range ports {
+		_, err := tx.Exec(
+			`INSERT INTO listening_ports (
+				system_info_id, pid, port, protocol, family, address, interface_name, interface_mac,
+				process_name, process_path, uid, username
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, port.PID, port.Port, port.Protocol, port.Family, port.Address, port.Interface, port.InterfaceMAC,
+			port.ProcessName, port.ProcessPath, port.UID, port.Username,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for listening port %d: %w", port.Port, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetLatestListeningPorts() ([]osquery.ListeningPort, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getListeningPorts(systemInfoID)
+}
+
+func (s *Service) getListeningPorts(systemInfoID int64) ([]osquery.ListeningPort, error) {
+	rows, err := s.db.Query(`
+		SELECT pid, port, protocol, family, address, interface_name, interface_mac,
+			process_name, process_path, uid, username
+		FROM listening_ports
+		WHERE system_info_id = ?
+		ORDER BY port, protocol, address
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listening ports: %w", err)
+	}
+	defer rows.Close()
+
+	ports := []osquery.ListeningPort{}
+	for rows.Next() {
+		var port osquery.ListeningPort
+		if err := rows.Scan(&port.PID, &port.Port, &port.Protocol, &port.Family, &port.Address, &port.Interface, &port.InterfaceMAC,
+			&port.ProcessName, &port.ProcessPath, &port.UID, &port.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan listening port row: %w", err)
+		}
+		ports = append(ports, port)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over listening port rows: %w", err)
+	}
+
+	return ports, nil
+}
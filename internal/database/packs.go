@@ -0,0 +1,202 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// StorePackResult stores rows as the next run of a pack query on host and
+// appends its differential events.
+func (s *Service) StorePackResult(host, pack, query string, snapshot bool, rows []osquery.Row, events []osquery.QueryEvent) error {
+	log := logger.Log.With(
+		zap.String("host", host),
+		zap.String("pack", pack),
+		zap.String("query", query),
+	)
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode pack result rows: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Error("Failed to begin database transaction",
+			zap.Error(err))
+		return fmt.Errorf("transaction error: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Error("Failed to rollback transaction",
+					zap.Error(rbErr))
+			}
+		}
+	}()
+
+	var run int64
+	err = tx.QueryRow(`
+		SELECT COALESCE(MAX(run_number), 0) + 1
+		FROM pack_results
+		WHERE host_identifier = ? AND pack_name = ? AND query_name = ?
+	`, host, pack, query).Scan(&run)
+	if err != nil {
+		return fmt.Errorf("failed to number pack query run '%s/%s': %w", pack, query, err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO pack_results (host_identifier, pack_name, query_name, run_number, snapshot, row_count, rows_json) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		host, pack, query, run, snapshot, len(rows), string(data),
+	)
+	if err != nil {
+		log.Error("Failed to insert pack result",
+			zap.Error(err))
+		return fmt.Errorf("database insert error for pack query '%s/%s': %w", pack, query, err)
+	}
+
+	for _, event := range events {
+		var columns []byte
+		columns, err = json.Marshal(event.Columns)
+		if err != nil {
+			return fmt.Errorf("failed to encode query event columns: %w", err)
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO query_events (name, host_identifier, action, unix_time, epoch, counter, columns_json) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			event.Name, event.HostIdentifier, event.Action, event.UnixTime, event.Epoch, event.Counter, string(columns),
+		)
+		if err != nil {
+			log.Error("Failed to insert query event",
+				zap.Error(err))
+			return fmt.Errorf("database insert error for query event '%s': %w", event.Name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("Failed to commit transaction",
+			zap.Error(err))
+		return fmt.Errorf("transaction commit error: %w", err)
+	}
+
+	return nil
+}
+
+// GetPreviousPackResult returns the rows of the newest stored run of a pack
+// query on host and its run number, which is how many runs came before the
+// next one.
+func (s *Service) GetPreviousPackResult(host, pack, query string) ([]osquery.Row, int64, error) {
+	var data string
+	var run int64
+	err := s.db.QueryRow(`
+		SELECT rows_json, run_number
+		FROM pack_results
+		WHERE host_identifier = ? AND pack_name = ? AND query_name = ?
+		ORDER BY run_number DESC
+		LIMIT 1
+	`, host, pack, query).Scan(&data, &run)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get previous pack result: %w", err)
+	}
+
+	rows, err := decodeRows(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode previous pack result rows: %w", err)
+	}
+
+	return rows, run, nil
+}
+
+// decodeRows decodes stored rows keeping numbers as json.Number, since
+// float64 would round integers above 2^53 and make unchanged rows differ.
+func decodeRows(data string) ([]osquery.Row, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var rows []osquery.Row
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *Service) GetQueryEvents(name string, sinceID int64, limit int) ([]model.QueryEventRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, host_identifier, action, unix_time, epoch, counter, columns_json
+		FROM query_events
+		WHERE (? = '' OR name = ?) AND id > ?
+		ORDER BY id
+		LIMIT ?
+	`, name, name, sinceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.QueryEventRecord{}
+	for rows.Next() {
+		var record model.QueryEventRecord
+		var columns string
+		event := &record.Event
+		if err := rows.Scan(&record.ID, &event.Name, &event.HostIdentifier, &event.Action, &event.UnixTime, &event.Epoch, &event.Counter, &columns); err != nil {
+			return nil, fmt.Errorf("failed to scan query event row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(columns), &event.Columns); err != nil {
+			return nil, fmt.Errorf("failed to decode query event columns: %w", err)
+		}
+		event.CalendarTime = osquery.CalendarTime(event.UnixTime)
+		events = append(events, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over query event rows: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetLatestPackResults returns the newest run of every pack query on every
+// host, optionally restricted to a single pack.
+func (s *Service) GetLatestPackResults(pack string) ([]model.PackResult, error) {
+	rows, err := s.db.Query(`
+		SELECT id, host_identifier, pack_name, query_name, run_number, snapshot, row_count, rows_json, collected_at
+		FROM pack_results
+		WHERE id IN (SELECT MAX(id) FROM pack_results GROUP BY host_identifier, pack_name, query_name)
+			AND (? = '' OR pack_name = ?)
+		ORDER BY pack_name, query_name, host_identifier
+	`, pack, pack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []model.PackResult{}
+	for rows.Next() {
+		var result model.PackResult
+		var data string
+		if err := rows.Scan(&result.ID, &result.HostIdentifier, &result.PackName, &result.QueryName, &result.Run, &result.Snapshot, &result.RowCount, &data, &result.CollectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pack result row: %w", err)
+		}
+		if result.Rows, err = decodeRows(data); err != nil {
+			return nil, fmt.Errorf("failed to decode pack result rows: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over pack result rows: %w", err)
+	}
+
+	return results, nil
+}
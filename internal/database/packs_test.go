@@ -0,0 +1,63 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func TestStorePackResultKeepsEveryRun(t *testing.T) {
+	s := newTestService(t)
+
+	runs := []struct {
+		host string
+		rows []osquery.Row
+	}{
+		{"uuid-a", []osquery.Row{{"port": json.Number("22")}}},
+		{"uuid-b", []osquery.Row{{"port": json.Number("80")}}},
+		{"uuid-a", []osquery.Row{{"port": json.Number("22")}, {"port": json.Number("443")}}},
+	}
+	for _, run := range runs {
+		if err := s.StorePackResult(run.host, "net", "ports", false, run.rows, nil); err != nil {
+			t.Fatalf("StorePackResult: %v", err)
+		}
+	}
+	if got := countRows(t, s, "pack_results"); got != len(runs) {
+		t.Fatalf("pack_results has %d rows, want one per run (%d)", got, len(runs))
+	}
+
+	// Each host diffs against its own newest run.
+	rows, run, err := s.GetPreviousPackResult("uuid-a", "net", "ports")
+	if err != nil {
+		t.Fatalf("GetPreviousPackResult: %v", err)
+	}
+	if run != 2 || !reflect.DeepEqual(rows, runs[2].rows) {
+		t.Fatalf("uuid-a previous = run %d %v, want run 2 %v", run, rows, runs[2].rows)
+	}
+	rows, run, err = s.GetPreviousPackResult("uuid-b", "net", "ports")
+	if err != nil {
+		t.Fatalf("GetPreviousPackResult: %v", err)
+	}
+	if run != 1 || !reflect.DeepEqual(rows, runs[1].rows) {
+		t.Fatalf("uuid-b previous = run %d %v, want run 1 %v", run, rows, runs[1].rows)
+	}
+	if rows, run, err := s.GetPreviousPackResult("uuid-c", "net", "ports"); err != nil || rows != nil || run != 0 {
+		t.Fatalf("unknown host previous = run %d %v, err %v, want nothing", run, rows, err)
+	}
+
+	latest, err := s.GetLatestPackResults("")
+	if err != nil {
+		t.Fatalf("GetLatestPackResults: %v", err)
+	}
+	if len(latest) != 2 {
+		t.Fatalf("latest results = %+v, want one per host", latest)
+	}
+	for _, result := range latest {
+		want := map[string]int64{"uuid-a": 2, "uuid-b": 1}[result.HostIdentifier]
+		if result.Run != want {
+			t.Errorf("%s latest run = %d, want %d", result.HostIdentifier, result.Run, want)
+		}
+	}
+}
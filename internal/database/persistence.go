@@ -0,0 +1,260 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storePersistence(tx *dialectTx, systemInfoID int64, persistence osquery.Persistence) error {
+	for _, unit := range persistence.SystemdUnits {
+		_, err := tx.Exec(
+			`INSERT INTO systemd_units (
+				system_info_id, unit_id, description, load_state, active_state, sub_state,
+				fragment_path, source_path, "user"
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, unit.ID, unit.Description, unit.LoadState, unit.ActiveState, unit.SubState,
+			unit.FragmentPath, unit.SourcePath, unit.User,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for systemd unit '%s': %w", unit.ID, err)
+		}
+	}
+
+	for _, job := range persistence.CronJobs {
+		_, err := tx.Exec(
+			`INSERT INTO crontab (
+				system_info_id, event, minute, hour, day_of_month, month, day_of_week, command, path
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, job.Event, job.Minute, job.Hour, job.DayOfMonth, job.Month, job.DayOfWeek, job.Command, job.Path,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for cron job in '%s': %w", job.Path, err)
+		}
+	}
+
+	for _, item := range persistence.StartupItems {
+		_, err := tx.Exec(
+			`INSERT INTO startup_items (
+				system_info_id, name, path, args, type, source, status, username
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, item.Name, item.Path, item.Args, item.Type, item.Source, item.Status, item.Username,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for startup item '%s': %w", item.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetLatestPersistence returns the persistence mechanisms of the latest
+// snapshot, flagging entries that were not present in the same host's
+// snapshot before it.
+func (s *Service) GetLatestPersistence() (model.Persistence, error) {
+	var persistence model.Persistence
+
+	latestID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return persistence, err
+	}
+	previousID, err := s.getPreviousSystemInfoID(latestID)
+	if err != nil {
+		return persistence, err
+	}
+	persistence.SnapshotID = latestID
+	persistence.PreviousSnapshotID = previousID
+
+	units, err := s.getSystemdUnits(latestID)
+	if err != nil {
+		return persistence, err
+	}
+	seen, err := previousKeys(s, previousID, osquery.CollectorSystemdUnits, s.getSystemdUnits)
+	if err != nil {
+		return persistence, err
+	}
+	persistence.SystemdUnits = make([]model.SystemdUnitRecord, 0, len(units))
+	for _, unit := range units {
+		persistence.SystemdUnits = append(persistence.SystemdUnits, model.SystemdUnitRecord{
+			SystemdUnit: unit,
+			New:         seen != nil && !seen[unit.Key()],
+		})
+	}
+
+	jobs, err := s.getCronJobs(latestID)
+	if err != nil {
+		return persistence, err
+	}
+	seen, err = previousKeys(s, previousID, osquery.CollectorCrontab, s.getCronJobs)
+	if err != nil {
+		return persistence, err
+	}
+	persistence.CronJobs = make([]model.CronJobRecord, 0, len(jobs))
+	for _, job := range jobs {
+		persistence.CronJobs = append(persistence.CronJobs, model.CronJobRecord{
+			CronJob: job,
+			New:     seen != nil && !seen[job.Key()],
+		})
+	}
+
+	items, err := s.getStartupItems(latestID)
+	if err != nil {
+		return persistence, err
+	}
+	seen, err = previousKeys(s, previousID, osquery.CollectorStartupItems, s.getStartupItems)
+	if err != nil {
+		return persistence, err
+	}
+	persistence.StartupItems = make([]model.StartupItemRecord, 0, len(items))
+	for _, item := range items {
+		persistence.StartupItems = append(persistence.StartupItems, model.StartupItemRecord{
+			StartupItem: item,
+			New:         seen != nil && !seen[item.Key()],
+		})
+	}
+
+	return persistence, nil
+}
+
+// previousKeys returns the keys of a collector's entries in the previous
+// snapshot, or nil when there is nothing to compare against.
+func previousKeys[T interface{ Key() string }](s *Service, previousID int64, collector string, load func(int64) ([]T, error)) (map[string]bool, error) {
+	if previousID == 0 {
+		return nil, nil
+	}
+
+	skipped, err := s.wasSkipped(previousID, collector)
+	if err != nil || skipped {
+		return nil, err
+	}
+
+	entries, err := load(previousID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		keys[entry.Key()] = true
+	}
+	return keys, nil
+}
+
+// getPreviousSystemInfoID returns the snapshot the same host, by
+// hardware_uuid, collected before id, or 0 when id is its first one.
+func (s *Service) getPreviousSystemInfoID(id int64) (int64, error) {
+	var previousID int64
+	err := s.db.QueryRow(`
+		SELECT id FROM system_info
+		WHERE hardware_uuid = (SELECT hardware_uuid FROM system_info WHERE id = ?)
+			AND id < ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, id, id).Scan(&previousID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get previous snapshot: %w", err)
+	}
+	return previousID, nil
+}
+
+func (s *Service) wasSkipped(systemInfoID int64, collector string) (bool, error) {
+	var count int
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM skipped_collectors WHERE system_info_id = ? AND collector = ?",
+		systemInfoID, collector,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check skipped collectors: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *Service) getSystemdUnits(systemInfoID int64) ([]osquery.SystemdUnit, error) {
+	rows, err := s.db.Query(`
+		SELECT unit_id, description, load_state, active_state, sub_state, fragment_path, source_path, "user"
+		FROM systemd_units
+		WHERE system_info_id = ?
+		ORDER BY unit_id
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systemd units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []osquery.SystemdUnit
+	for rows.Next() {
+		var unit osquery.SystemdUnit
+		if err := rows.Scan(&unit.ID, &unit.Description, &unit.LoadState, &unit.ActiveState, &unit.SubState,
+			&unit.FragmentPath, &unit.SourcePath, &unit.User); err != nil {
+			return nil, fmt.Errorf("failed to scan systemd unit row: %w", err)
+		}
+		units = append(units, unit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over systemd unit rows: %w", err)
+	}
+	return units, nil
+}
+
+func (s *Service) getCronJobs(systemInfoID int64) ([]osquery.CronJob, error) {
+	rows, err := s.db.Query(`
+		SELECT event, minute, hour, day_of_month, month, day_of_week, command, path
+		FROM crontab
+		WHERE system_info_id = ?
+		ORDER BY path, id
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cron jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []osquery.CronJob
+	for rows.Next() {
+		var job osquery.CronJob
+		if err := rows.Scan(&job.Event, &job.Minute, &job.Hour, &job.DayOfMonth, &job.Month, &job.DayOfWeek,
+			&job.Command, &job.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan cron job row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over cron job rows: %w", err)
+	}
+	return jobs, nil
+}
+
+func (s *Service) getStartupItems(systemInfoID int64) ([]osquery.StartupItem, error) {
+	rows, err := s.db.Query(`
+		SELECT name, path, args, type, source, status, username
+		FROM startup_items
+		WHERE system_info_id = ?
+		ORDER BY name, path
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get startup items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []osquery.StartupItem
+	for rows.Next() {
+		var item osquery.StartupItem
+		if err := rows.Scan(&item.Name, &item.Path, &item.Args, &item.Type, &item.Source, &item.Status,
+			&item.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan startup item row: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over startup item rows: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	"github.com/lib/pq"
+)
+
+// NewPostgresService connects to the Postgres database at dsn.
+func NewPostgresService(dsn string) (*Service, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres database: %w", err)
+	}
+	db.SetConnMaxLifetime(time.Hour)
+
+	return newService(db, dialectPostgres), nil
+}
+
+// copySoftwareSetMembers bulk loads a new software set's members with COPY,
+// which is much faster than INSERT on hosts with thousands of packages.
+func copySoftwareSetMembers(tx *dialectTx, setID int64, apps []osquery.InstalledApp, softwareIDs map[softwareKey]int64) error {
+	stmt, err := tx.Prepare(pq.CopyIn("software_set_members",
+		"software_set_id", "software_id", "arch", "vendor", "install_time"))
+	if err != nil {
+		return fmt.Errorf("failed to start copy of software set members: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, app := range apps {
+		if _, err := stmt.Exec(setID, softwareIDs[appSoftwareKey(app)], app.Arch, app.Vendor, app.InstallTime); err != nil {
+			return fmt.Errorf("database copy error for app '%s': %w", app.Name, err)
+		}
+	}
+
+	if _, err := stmt.Exec(); err != nil {
+		return fmt.Errorf("database copy error for software set members: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func storeProcesses(tx *dialectTx, systemInfoID int64, processes []osquery.Process) error {
+	for _, process := range processes {
+		_, err := tx.Exec(
+			`INSERT INTO processes (
+				system_info_id, pid, parent, name, path, cmdline, uid,
+				start_time, resident_size, user_time, system_time
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			systemInfoID, process.PID, process.Parent, process.Name, process.Path, process.Cmdline, process.UID,
+			process.StartTime, process.ResidentSize, process.UserTime, process.SystemTime,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert error for process %d: %w", process.PID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetLatestProcesses() ([]osquery.Process, error) {
+	systemInfoID, err := s.getLatestSystemInfoID()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT pid, parent, name, path, cmdline, uid,
+			start_time, resident_size, user_time, system_time
+		FROM processes
+		WHERE system_info_id = ?
+		ORDER BY pid
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processes: %w", err)
+	}
+	defer rows.Close()
+
+	processes := []osquery.Process{}
+	for rows.Next() {
+		var process osquery.Process
+		if err := rows.Scan(&process.PID, &process.Parent, &process.Name, &process.Path, &process.Cmdline, &process.UID,
+			&process.StartTime, &process.ResidentSize, &process.UserTime, &process.SystemTime); err != nil {
+			return nil, fmt.Errorf("failed to scan process row: %w", err)
+		}
+		processes = append(processes, process)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over process rows: %w", err)
+	}
+
+	return processes, nil
+}
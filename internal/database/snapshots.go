@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+var (
+	// ErrSnapshotNotFound is returned when a requested snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrNoPreviousSnapshot is returned when a diff is asked for the snapshot
+	// before one that has none.
+	ErrNoPreviousSnapshot = errors.New("no previous snapshot")
+)
+
+// ListSnapshots returns the most recent stored snapshots, newest first.
+func (s *Service) ListSnapshots(limit int) ([]model.SnapshotSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT id, hostname, hardware_uuid, os_name, os_version, osquery_version, collected_at
+		FROM system_info
+		ORDER BY collected_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []model.SnapshotSummary{}
+	for rows.Next() {
+		var snapshot model.SnapshotSummary
+		if err := rows.Scan(&snapshot.ID, &snapshot.Hostname, &snapshot.HardwareUUID, &snapshot.OSName,
+			&snapshot.OSVersion, &snapshot.OsqueryVersion, &snapshot.CollectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot row: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over snapshot rows: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+func (s *Service) getSnapshotSummary(id int64) (model.SnapshotSummary, error) {
+	var snapshot model.SnapshotSummary
+	err := s.db.QueryRow(`
+		SELECT id, hostname, hardware_uuid, os_name, os_version, osquery_version, collected_at
+		FROM system_info
+		WHERE id = ?
+	`, id).Scan(&snapshot.ID, &snapshot.Hostname, &snapshot.HardwareUUID, &snapshot.OSName,
+		&snapshot.OSVersion, &snapshot.OsqueryVersion, &snapshot.CollectedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return snapshot, fmt.Errorf("snapshot %d: %w", id, ErrSnapshotNotFound)
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to get snapshot %d: %w", id, err)
+	}
+	return snapshot, nil
+}
+
+// DiffSnapshots compares the installed apps and listening ports of two stored
+// snapshots. Apps are matched by name, source and arch; ports by protocol,
+// address, port and owning process path, so a restarted process is not a change.
+// A zero toID means the latest snapshot and a zero fromID the same host's
+// snapshot before toID.
+func (s *Service) DiffSnapshots(fromID, toID int64) (*model.SnapshotDiff, error) {
+	var err error
+	if toID == 0 {
+		toID, err = s.getLatestSystemInfoID()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSnapshotNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fromID == 0 {
+		if fromID, err = s.getPreviousSystemInfoID(toID); err != nil {
+			return nil, err
+		}
+		if fromID == 0 {
+			return nil, fmt.Errorf("snapshot %d: %w", toID, ErrNoPreviousSnapshot)
+		}
+	}
+
+	to, err := s.getSnapshotSummary(toID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.getSnapshotSummary(fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromApps, err := s.getInstalledApps(fromID)
+	if err != nil {
+		return nil, err
+	}
+	toApps, err := s.getInstalledApps(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromPorts, err := s.getListeningPorts(fromID)
+	if err != nil {
+		return nil, err
+	}
+	toPorts, err := s.getListeningPorts(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &model.SnapshotDiff{
+		From:         from,
+		To:           to,
+		AppsAdded:    []osquery.InstalledApp{},
+		AppsRemoved:  []osquery.InstalledApp{},
+		AppsChanged:  []model.AppVersionChange{},
+		PortsAdded:   []osquery.ListeningPort{},
+		PortsRemoved: []osquery.ListeningPort{},
+	}
+
+	previousApps := make(map[string]bool, len(fromApps))
+	for _, app := range fromApps {
+		previousApps[appKey(app)] = true
+	}
+	currentApps := make(map[string]bool, len(toApps))
+	var added, removed []osquery.InstalledApp
+	for _, app := range toApps {
+		currentApps[appKey(app)] = true
+		if !previousApps[appKey(app)] {
+			added = append(added, app)
+		}
+	}
+	for _, app := range fromApps {
+		if !currentApps[appKey(app)] {
+			removed = append(removed, app)
+		}
+	}
+
+	// An app that went from exactly one version to exactly one other is a
+	// version change. Apps installed in several versions side by side, such
+	// as kernels, keep their versions as separate additions and removals.
+	addedByPackage := make(map[string][]osquery.InstalledApp)
+	for _, app := range added {
+		addedByPackage[packageKey(app)] = append(addedByPackage[packageKey(app)], app)
+	}
+	removedByPackage := make(map[string][]osquery.InstalledApp)
+	for _, app := range removed {
+		removedByPackage[packageKey(app)] = append(removedByPackage[packageKey(app)], app)
+	}
+	changed := func(app osquery.InstalledApp) bool {
+		return len(addedByPackage[packageKey(app)]) == 1 && len(removedByPackage[packageKey(app)]) == 1
+	}
+	for _, app := range added {
+		if !changed(app) {
+			diff.AppsAdded = append(diff.AppsAdded, app)
+			continue
+		}
+		diff.AppsChanged = append(diff.AppsChanged, model.AppVersionChange{
+			Name:        app.Name,
+			Source:      app.Source,
+			FromVersion: removedByPackage[packageKey(app)][0].Version,
+			ToVersion:   app.Version,
+		})
+	}
+	for _, app := range removed {
+		if !changed(app) {
+			diff.AppsRemoved = append(diff.AppsRemoved, app)
+		}
+	}
+
+	previousPorts := make(map[string]bool, len(fromPorts))
+	for _, port := range fromPorts {
+		previousPorts[portKey(port)] = true
+	}
+	currentPorts := make(map[string]bool, len(toPorts))
+	for _, port := range toPorts {
+		currentPorts[portKey(port)] = true
+		if !previousPorts[portKey(port)] {
+			diff.PortsAdded = append(diff.PortsAdded, port)
+		}
+	}
+	for _, port := range fromPorts {
+		if !currentPorts[portKey(port)] {
+			diff.PortsRemoved = append(diff.PortsRemoved, port)
+		}
+	}
+
+	return diff, nil
+}
+
+func appKey(app osquery.InstalledApp) string {
+	return packageKey(app) + "\x00" + app.Version
+}
+
+func packageKey(app osquery.InstalledApp) string {
+	return app.Source + "\x00" + app.Name + "\x00" + app.Arch
+}
+
+func portKey(port osquery.ListeningPort) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s", port.Protocol, port.Address, port.Port, port.ProcessPath)
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	s := newTestService(t)
+	sshd := osquery.ListeningPort{PID: 100, Port: 22, Protocol: "tcp", Family: 2, Address: "0.0.0.0", ProcessPath: "/usr/sbin/sshd"}
+	nginx := osquery.ListeningPort{PID: 200, Port: 80, Protocol: "tcp", Family: 2, Address: "0.0.0.0", ProcessPath: "/usr/sbin/nginx"}
+	restartedSSHD := sshd
+	restartedSSHD.PID = 101
+
+	first := storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{
+		{Name: "curl", Version: "8.5.0", Source: "deb"},
+		{Name: "telnet", Version: "0.17", Source: "deb"},
+	}, []osquery.ListeningPort{sshd}))
+	second := storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{
+		{Name: "curl", Version: "8.6.0", Source: "deb"},
+		{Name: "nginx", Version: "1.24.0", Source: "deb"},
+	}, []osquery.ListeningPort{restartedSSHD, nginx}))
+
+	diff, err := s.DiffSnapshots(0, second)
+	if err != nil {
+		t.Fatalf("DiffSnapshots: %v", err)
+	}
+	if diff.From.ID != first || diff.To.ID != second {
+		t.Fatalf("diff compares %d to %d, want %d to %d", diff.From.ID, diff.To.ID, first, second)
+	}
+	if len(diff.AppsAdded) != 1 || diff.AppsAdded[0].Name != "nginx" {
+		t.Errorf("apps added = %+v", diff.AppsAdded)
+	}
+	if len(diff.AppsRemoved) != 1 || diff.AppsRemoved[0].Name != "telnet" {
+		t.Errorf("apps removed = %+v", diff.AppsRemoved)
+	}
+	wantChanged := []model.AppVersionChange{{Name: "curl", Source: "deb", FromVersion: "8.5.0", ToVersion: "8.6.0"}}
+	if !reflect.DeepEqual(diff.AppsChanged, wantChanged) {
+		t.Errorf("apps changed = %+v, want %+v", diff.AppsChanged, wantChanged)
+	}
+	if len(diff.PortsAdded) != 1 || diff.PortsAdded[0].Port != 80 || len(diff.PortsRemoved) != 0 {
+		t.Errorf("ports added = %+v, removed = %+v", diff.PortsAdded, diff.PortsRemoved)
+	}
+
+	if _, err := s.DiffSnapshots(0, first); !errors.Is(err, ErrNoPreviousSnapshot) {
+		t.Errorf("DiffSnapshots of a first snapshot: err = %v, want %v", err, ErrNoPreviousSnapshot)
+	}
+	if _, err := s.DiffSnapshots(first, 999); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("DiffSnapshots of an unknown snapshot: err = %v, want %v", err, ErrSnapshotNotFound)
+	}
+}
+
+func TestDiffSnapshotsEmptyDatabase(t *testing.T) {
+	s := newTestService(t)
+
+	if _, err := s.DiffSnapshots(0, 0); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrSnapshotNotFound)
+	}
+}
+
+func TestDiffSnapshotsSameHost(t *testing.T) {
+	s := newTestService(t)
+	curl := osquery.InstalledApp{Name: "curl", Version: "8.5.0", Source: "deb"}
+	vim := osquery.InstalledApp{Name: "vim", Version: "9.1", Source: "deb"}
+
+	first := storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{curl}, nil))
+	other := storeSnapshot(t, s, testSnapshot("uuid-2", []osquery.InstalledApp{vim}, nil))
+	third := storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{curl}, nil))
+
+	diff, err := s.DiffSnapshots(0, 0)
+	if err != nil {
+		t.Fatalf("DiffSnapshots: %v", err)
+	}
+	if diff.From.ID != first || diff.To.ID != third {
+		t.Fatalf("diff compares %d to %d, want the same host's %d to %d", diff.From.ID, diff.To.ID, first, third)
+	}
+	if len(diff.AppsAdded) != 0 || len(diff.AppsRemoved) != 0 {
+		t.Fatalf("diff against another host's snapshot: added %+v, removed %+v", diff.AppsAdded, diff.AppsRemoved)
+	}
+
+	if _, err := s.DiffSnapshots(0, other); !errors.Is(err, ErrNoPreviousSnapshot) {
+		t.Fatalf("DiffSnapshots of a host's only snapshot: err = %v, want %v", err, ErrNoPreviousSnapshot)
+	}
+}
+
+// Versions installed side by side are added and removed one by one rather
+// than reported as a change.
+func TestDiffSnapshotsSideBySideVersions(t *testing.T) {
+	s := newTestService(t)
+	kernel := func(version string) osquery.InstalledApp {
+		return osquery.InstalledApp{Name: "kernel", Version: version, Source: "rpm", Arch: "x86_64"}
+	}
+
+	storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{kernel("6.1"), kernel("6.2")}, nil))
+	second := storeSnapshot(t, s, testSnapshot("uuid-1", []osquery.InstalledApp{kernel("6.1"), kernel("6.2"), kernel("6.3")}, nil))
+
+	diff, err := s.DiffSnapshots(0, second)
+	if err != nil {
+		t.Fatalf("DiffSnapshots: %v", err)
+	}
+	if want := []osquery.InstalledApp{kernel("6.3")}; !reflect.DeepEqual(diff.AppsAdded, want) {
+		t.Errorf("apps added = %+v, want %+v", diff.AppsAdded, want)
+	}
+	if len(diff.AppsRemoved) != 0 || len(diff.AppsChanged) != 0 {
+		t.Errorf("apps removed = %+v, changed = %+v, want none", diff.AppsRemoved, diff.AppsChanged)
+	}
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+const (
+	// maxInsertParams bounds the placeholders in one multi-row INSERT, well
+	// under the limits of every supported database.
+	maxInsertParams = 5000
+
+	softwareLookupBatch = 500
+)
+
+// softwareKey identifies an entry of the software catalog.
+type softwareKey struct {
+	Name    string
+	Version string
+	Source  string
+}
+
+func appSoftwareKey(app osquery.InstalledApp) softwareKey {
+	return softwareKey{Name: app.Name, Version: app.Version, Source: app.Source}
+}
+
+// softwareSetHash is the sha256 of a snapshot's app list, independent of the
+// order osquery returned it in. Every field is length prefixed so that no
+// two different lists, however their names and versions are spelled, encode
+// to the same bytes.
+func softwareSetHash(apps []osquery.InstalledApp) string {
+	entries := make([]string, len(apps))
+	for i, app := range apps {
+		var entry strings.Builder
+		for _, field := range []string{
+			app.Name, app.Version, app.Source, app.Arch, app.Vendor, strconv.FormatInt(app.InstallTime, 10),
+		} {
+			entry.WriteString(strconv.Itoa(len(field)))
+			entry.WriteByte(':')
+			entry.WriteString(field)
+		}
+		entries[i] = entry.String()
+	}
+	sort.Strings(entries)
+
+	sum := sha256.New()
+	for _, entry := range entries {
+		sum.Write([]byte(strconv.Itoa(len(entry)) + ":" + entry))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// storeSoftwareSet returns the id of the software set holding exactly apps,
+// creating it along with any new catalog entries when no earlier snapshot had
+// the same list. A reused set only has its last_seen bumped. A snapshot
+// without apps has no set.
+func storeSoftwareSet(tx *dialectTx, apps []osquery.InstalledApp) (sql.NullInt64, error) {
+	if len(apps) == 0 {
+		return sql.NullInt64{}, nil
+	}
+
+	hash := softwareSetHash(apps)
+	result, err := tx.Exec(tx.dialect.ignoreDuplicates(
+		"INSERT INTO software_sets (content_hash, app_count) VALUES (?, ?)"), hash, len(apps))
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("database insert error for software set: %w", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("database insert error for software set: %w", err)
+	}
+
+	var setID int64
+	if err := tx.QueryRow("SELECT id FROM software_sets WHERE content_hash = ?"+tx.dialect.forShare(), hash).Scan(&setID); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("failed to get software set: %w", err)
+	}
+
+	if created == 0 {
+		if _, err := tx.Exec("UPDATE software_sets SET last_seen = CURRENT_TIMESTAMP WHERE id = ?", setID); err != nil {
+			return sql.NullInt64{}, fmt.Errorf("database update error for software set %d: %w", setID, err)
+		}
+		return sql.NullInt64{Int64: setID, Valid: true}, nil
+	}
+
+	softwareIDs, err := resolveSoftware(tx, apps)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	if tx.dialect == dialectPostgres {
+		err = copySoftwareSetMembers(tx, setID, apps, softwareIDs)
+	} else {
+		rows := make([][]interface{}, len(apps))
+		for i, app := range apps {
+			rows[i] = []interface{}{setID, softwareIDs[appSoftwareKey(app)], app.Arch, app.Vendor, app.InstallTime}
+		}
+		err = insertRows(tx, "software_set_members",
+			[]string{"software_set_id", "software_id", "arch", "vendor", "install_time"}, rows, false)
+	}
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: setID, Valid: true}, nil
+}
+
+// resolveSoftware returns the catalog id of every app, adding the ones the
+// catalog does not have yet.
+func resolveSoftware(tx *dialectTx, apps []osquery.InstalledApp) (map[softwareKey]int64, error) {
+	ids := map[softwareKey]int64{}
+	var keys []softwareKey
+	for _, app := range apps {
+		key := appSoftwareKey(app)
+		if _, ok := ids[key]; !ok {
+			ids[key] = 0
+			keys = append(keys, key)
+		}
+	}
+
+	if err := lookupSoftware(tx, keys, ids, false); err != nil {
+		return nil, err
+	}
+
+	var missing []softwareKey
+	var rows [][]interface{}
+	for _, key := range keys {
+		if ids[key] == 0 {
+			missing = append(missing, key)
+			rows = append(rows, []interface{}{key.Name, key.Version, key.Source})
+		}
+	}
+	if len(missing) == 0 {
+		return ids, nil
+	}
+
+	// Another collector may add the same software concurrently, so insert
+	// what is missing ignoring duplicates and read the ids back.
+	if err := insertRows(tx, "software", []string{"name", "version", "source"}, rows, true); err != nil {
+		return nil, err
+	}
+	if err := lookupSoftware(tx, missing, ids, true); err != nil {
+		return nil, err
+	}
+
+	for _, key := range missing {
+		if ids[key] == 0 {
+			return nil, fmt.Errorf("software '%s' %s (%s) missing from catalog after insert", key.Name, key.Version, key.Source)
+		}
+	}
+	return ids, nil
+}
+
+func lookupSoftware(tx *dialectTx, keys []softwareKey, ids map[softwareKey]int64, lock bool) error {
+	for start := 0; start < len(keys); start += softwareLookupBatch {
+		end := start + softwareLookupBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		names := make([]interface{}, 0, end-start)
+		for _, key := range keys[start:end] {
+			names = append(names, key.Name)
+		}
+
+		query := "SELECT id, name, version, source FROM software WHERE name IN (" + placeholders(len(names)) + ")"
+		if lock {
+			query += tx.dialect.forShare()
+		}
+		rows, err := tx.Query(query, names...)
+		if err != nil {
+			return fmt.Errorf("failed to get software: %w", err)
+		}
+
+		for rows.Next() {
+			var id int64
+			var key softwareKey
+			if err := rows.Scan(&id, &key.Name, &key.Version, &key.Source); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan software row: %w", err)
+			}
+			if _, ok := ids[key]; ok {
+				ids[key] = id
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over software rows: %w", err)
+		}
+	}
+	return nil
+}
+
+// insertRows writes rows into table with as few multi-row INSERT statements
+// as maxInsertParams allows.
+func insertRows(tx *dialectTx, table string, columns []string, rows [][]interface{}, ignoreDuplicates bool) error {
+	perStatement := maxInsertParams / len(columns)
+	prefix := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES "
+	row := "(" + placeholders(len(columns)) + ")"
+
+	for start := 0; start < len(rows); start += perStatement {
+		end := start + perStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, r := range rows[start:end] {
+			values = append(values, row)
+			args = append(args, r...)
+		}
+
+		query := prefix + strings.Join(values, ", ")
+		if ignoreDuplicates {
+			query = tx.dialect.ignoreDuplicates(query)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("database insert error for %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func (s *Service) getInstalledApps(systemInfoID int64) ([]osquery.InstalledApp, error) {
+	rows, err := s.db.Query(`
+		SELECT sw.name, sw.version, sw.source, m.arch, m.vendor, m.install_time
+		FROM system_info si
+		JOIN software_set_members m ON m.software_set_id = si.software_set_id
+		JOIN software sw ON sw.id = m.software_id
+		WHERE si.id = ?
+		ORDER BY m.id
+	`, systemInfoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installed apps: %w", err)
+	}
+	defer rows.Close()
+
+	apps := []osquery.InstalledApp{}
+	for rows.Next() {
+		var app osquery.InstalledApp
+		if err := rows.Scan(&app.Name, &app.Version, &app.Source, &app.Arch, &app.Vendor, &app.InstallTime); err != nil {
+			return nil, fmt.Errorf("failed to scan app row: %w", err)
+		}
+		apps = append(apps, app)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over app rows: %w", err)
+	}
+
+	return apps, nil
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	s, err := NewSQLiteService(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteService: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	if err := s.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	return s
+}
+
+func testSnapshot(hardwareUUID string, apps []osquery.InstalledApp, ports []osquery.ListeningPort) osquery.Snapshot {
+	return osquery.Snapshot{
+		SystemInfo: osquery.SystemInfoResult{
+			OSVersion:      "22.04",
+			OSName:         "Ubuntu",
+			OSPlatform:     "ubuntu",
+			OsqueryVersion: "5.11.0",
+			Hostname:       "host-" + hardwareUUID,
+			HardwareUUID:   hardwareUUID,
+			PhysicalMemory: 8 << 30,
+		},
+		Apps:           apps,
+		ListeningPorts: ports,
+	}
+}
+
+func storeSnapshot(t *testing.T, s *Service, snapshot osquery.Snapshot) int64 {
+	t.Helper()
+
+	if err := s.StoreSnapshot(snapshot); err != nil {
+		t.Fatalf("StoreSnapshot: %v", err)
+	}
+	id, err := s.getLatestSystemInfoID()
+	if err != nil {
+		t.Fatalf("getLatestSystemInfoID: %v", err)
+	}
+	return id
+}
+
+func countRows(t *testing.T, s *Service, table string) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestStoreSnapshotReusesSoftwareSet(t *testing.T) {
+	s := newTestService(t)
+	apps := []osquery.InstalledApp{
+		{Name: "curl", Version: "8.5.0", Source: "deb"},
+		{Name: "git", Version: "2.43.0", Source: "deb"},
+	}
+	reordered := []osquery.InstalledApp{apps[1], apps[0]}
+
+	first := storeSnapshot(t, s, testSnapshot("uuid-1", apps, nil))
+	second := storeSnapshot(t, s, testSnapshot("uuid-2", reordered, nil))
+
+	if got := countRows(t, s, "software_sets"); got != 1 {
+		t.Fatalf("software_sets = %d after an identical app list, want 1", got)
+	}
+	if got := countRows(t, s, "software_set_members"); got != len(apps) {
+		t.Fatalf("software_set_members = %d, want %d", got, len(apps))
+	}
+	for _, id := range []int64{first, second} {
+		got, err := s.getInstalledApps(id)
+		if err != nil {
+			t.Fatalf("getInstalledApps(%d): %v", id, err)
+		}
+		if len(got) != len(apps) {
+			t.Fatalf("snapshot %d has %d apps, want %d", id, len(got), len(apps))
+		}
+	}
+
+	upgraded := []osquery.InstalledApp{apps[0], {Name: "git", Version: "2.44.0", Source: "deb"}}
+	storeSnapshot(t, s, testSnapshot("uuid-1", upgraded, nil))
+
+	if got := countRows(t, s, "software_sets"); got != 2 {
+		t.Fatalf("software_sets = %d after a changed app list, want 2", got)
+	}
+	if got := countRows(t, s, "software"); got != 3 {
+		t.Fatalf("software = %d, want the catalog to share curl and add one git", got)
+	}
+
+	storeSnapshot(t, s, testSnapshot("uuid-1", nil, nil))
+	if got := countRows(t, s, "software_sets"); got != 2 {
+		t.Fatalf("software_sets = %d after a snapshot without apps, want 2", got)
+	}
+}
+
+func TestSoftwareSetHash(t *testing.T) {
+	apps := []osquery.InstalledApp{
+		{Name: "curl", Version: "8.5.0", Source: "deb", Arch: "amd64"},
+		{Name: "git", Version: "2.43.0", Source: "deb", Arch: "amd64"},
+	}
+
+	if softwareSetHash(apps) != softwareSetHash([]osquery.InstalledApp{apps[1], apps[0]}) {
+		t.Error("hash depends on app order")
+	}
+
+	for name, change := range map[string]func(*osquery.InstalledApp){
+		"name":    func(app *osquery.InstalledApp) { app.Name = "git-core" },
+		"version": func(app *osquery.InstalledApp) { app.Version = "2.43.1" },
+		"source":  func(app *osquery.InstalledApp) { app.Source = "rpm" },
+		"arch":    func(app *osquery.InstalledApp) { app.Arch = "arm64" },
+	} {
+		changed := []osquery.InstalledApp{apps[0], apps[1]}
+		change(&changed[1])
+		if softwareSetHash(apps) == softwareSetHash(changed) {
+			t.Errorf("hash ignores a changed %s", name)
+		}
+	}
+
+	if softwareSetHash(apps) == softwareSetHash(append(apps[:2:2], apps[1])) {
+		t.Error("hash ignores a duplicated app")
+	}
+
+	// Separators inside fields do not make different lists collide.
+	joined := []osquery.InstalledApp{{Name: "a\x00b", Version: "1"}}
+	split := []osquery.InstalledApp{{Name: "a", Version: "b\x001"}}
+	if softwareSetHash(joined) == softwareSetHash(split) {
+		t.Error("hash confuses field boundaries")
+	}
+}
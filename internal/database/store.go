@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	_ "modernc.org/sqlite"
+)
+
+// Store is the storage every backend provides: write a snapshot, read the
+// latest one back, list the stored snapshots and diff two of them.
+type Store interface {
+	StoreSnapshot(snapshot osquery.Snapshot) error
+	GetLatestSystemInfo() (*model.SystemInfo, error)
+	ListSnapshots(limit int) ([]model.SnapshotSummary, error)
+	DiffSnapshots(fromID, toID int64) (*model.SnapshotDiff, error)
+	Close() error
+}
+
+// Migrator is implemented by backends with a versioned schema.
+type Migrator interface {
+	MigrateUp(ctx context.Context) error
+	MigrateDown(ctx context.Context, steps int) error
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// PackStore is implemented by backends that keep scheduled pack results and
+// the differential events computed from them.
+type PackStore interface {
+	StorePackResult(host, pack, query string, snapshot bool, rows []osquery.Row, events []osquery.QueryEvent) error
+	GetPreviousPackResult(host, pack, query string) ([]osquery.Row, int64, error)
+	GetQueryEvents(name string, sinceID int64, limit int) ([]model.QueryEventRecord, error)
+	GetLatestPackResults(pack string) ([]model.PackResult, error)
+}
+
+// InventoryStore is implemented by backends that serve what the individual
+// collectors found in the latest snapshots.
+type InventoryStore interface {
+	GetLatestListeningPorts() ([]osquery.ListeningPort, error)
+	GetSudoAccounts(includeGone bool) ([]model.SudoAccountRecord, error)
+	GetAuthorizedKeys(includeGone bool) ([]model.AuthorizedKeyRecord, error)
+	GetLatestProcesses() ([]osquery.Process, error)
+	GetLatestPersistence() (model.Persistence, error)
+	GetExpiringCertificates(within time.Duration) ([]model.CertificateRecord, error)
+	GetFileChanges(path, changeType string, sinceID int64, limit int) ([]model.FileChangeRecord, error)
+	GetLatestMounts() ([]model.MountUsage, error)
+	GetMountHistory(path string, since time.Time) ([]model.MountUsage, error)
+	GetLatestKernelModules() ([]osquery.KernelModule, error)
+	GetLatestSystemControls() ([]osquery.SystemControl, error)
+	GetHostSystemControls() ([]model.HostSystemControls, error)
+	GetLatestContainers() ([]osquery.Container, error)
+	GetLatestImages() ([]osquery.DockerImage, error)
+	GetLatestExtensions() ([]osquery.Extension, error)
+	FindExtensionInstalls(identifier, name string) ([]model.ExtensionInstall, error)
+}
+
+var (
+	_ Store          = (*Service)(nil)
+	_ Migrator       = (*Service)(nil)
+	_ PackStore      = (*Service)(nil)
+	_ InventoryStore = (*Service)(nil)
+)
+
+// NewSQLiteService opens the SQLite database at path, creating the file if it
+// does not exist. The driver is pure Go, so builds with CGO_ENABLED=0 keep
+// SQLite support. SQLite allows a single writer, so the pool is limited to
+// one connection and transactions take the write lock up front.
+func NewSQLiteService(path string) (*Service, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate&_time_format=sqlite", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	return newService(db, dialectSQLite), nil
+}
+
+// likePrefix returns a LIKE pattern, used with ESCAPE '!', that matches
+// values starting with s.
+func likePrefix(s string) string {
+	return escapeLike(s) + "%"
+}
+
+// likeContains returns a LIKE pattern, used with ESCAPE '!', that matches
+// values containing s.
+func likeContains(s string) string {
+	return "%" + escapeLike(s) + "%"
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// timestamp scans a time that the driver may return as text, which SQLite
+// does for aggregates such as MIN(collected_at).
+type timestamp struct {
+	time *time.Time
+}
+
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+func (t timestamp) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		*t.time = v
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case nil:
+		*t.time = time.Time{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", value)
+	}
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			*t.time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse timestamp %q", text)
+}
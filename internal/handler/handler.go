@@ -2,16 +2,53 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Siddharth9890/osquery-mvp/internal/database"
+	model "github.com/Siddharth9890/osquery-mvp/internal/models"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
 	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
 	"github.com/Siddharth9890/osquery-mvp/pkg/middleware"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultEventLimit = 1000
+	maxEventLimit     = 10000
+
+	defaultMountHistoryWindow = 7 * 24 * time.Hour
+
+	defaultSnapshotLimit = 50
+	maxSnapshotLimit     = 1000
+
+	notSupportedMessage = "Not supported by the configured storage backend"
+)
+
+var errNotSupported = errors.New("not supported by storage backend")
+
 type Handler struct {
-	dbService *database.Service
+	dbService database.Store
+	options   Options
+
+	// inventory and packs are nil when the storage backend does not keep
+	// collector inventory or pack results, and their endpoints answer 501.
+	inventory database.InventoryStore
+	packs     database.PackStore
+}
+
+type Options struct {
+	CertificateExpiryWindow time.Duration
+
+	// DiskUsageThreshold and InodeUsageThreshold are the percentages of a
+	// mount's space or inodes in use above which the host is degraded.
+	DiskUsageThreshold  float64
+	InodeUsageThreshold float64
+
+	SysctlBaseline osquery.SysctlBaseline
 }
 
 type Response struct {
@@ -20,8 +57,14 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-func NewHandler(dbService *database.Service) *Handler {
-	return &Handler{dbService: dbService}
+func NewHandler(dbService database.Store, options Options) *Handler {
+	h := &Handler{
+		dbService: dbService,
+		options:   options,
+	}
+	h.inventory, _ = dbService.(database.InventoryStore)
+	h.packs, _ = dbService.(database.PackStore)
+	return h
 }
 
 func (h *Handler) GetLatestData(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +91,19 @@ func (h *Handler) GetLatestData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Health is left out rather than failing the whole response, the same
+	// way a skipped collector is.
+	if h.inventory != nil {
+		mounts, err := h.inventory.GetLatestMounts()
+		if err != nil {
+			log.Error("Failed to retrieve mounts for health check, omitting health",
+				zap.Error(err))
+		} else {
+			health := h.checkMounts(mounts)
+			info.Health = &health
+		}
+	}
+
 	log.Debug("Retrieved latest system info",
 		zap.String("os_version", info.OSVersion),
 		zap.String("osquery_version", info.OsqueryVersion),
@@ -61,6 +117,541 @@ func (h *Handler) GetLatestData(w http.ResponseWriter, r *http.Request) {
 	log.Info("Successfully responded with latest data")
 }
 
+func (h *Handler) GetPackResults(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestIDFromContext(r.Context())
+	log := logger.WithRequestID(requestID)
+
+	log.Info("Processing pack results request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed",
+			zap.String("method", r.Method))
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.packs == nil {
+		respondWithError(w, http.StatusNotImplemented, notSupportedMessage)
+		return
+	}
+
+	pack := r.URL.Query().Get("pack")
+	results, err := h.packs.GetLatestPackResults(pack)
+	if err != nil {
+		log.Error("Failed to retrieve pack results",
+			zap.Error(err))
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve pack results")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Response{
+		Success: true,
+		Data:    results,
+	})
+
+	log.Info("Successfully responded with pack results",
+		zap.Int("result_count", len(results)))
+}
+
+// GetQueryEvents serves differential results in osquery's event format. With
+// ?format=ndjson the body matches osqueryd.results.log line for line.
+func (h *Handler) GetQueryEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestIDFromContext(r.Context())
+	log := logger.WithRequestID(requestID)
+
+	log.Info("Processing query events request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed",
+			zap.String("method", r.Method))
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.packs == nil {
+		respondWithError(w, http.StatusNotImplemented, notSupportedMessage)
+		return
+	}
+
+	params := r.URL.Query()
+	sinceID, err := parseInt64Param(params.Get("since"), 0)
+	if err != nil || sinceID < 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid since parameter")
+		return
+	}
+	limit, err := parseInt64Param(params.Get("limit"), defaultEventLimit)
+	if err != nil || limit <= 0 || limit > maxEventLimit {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+		return
+	}
+
+	records, err := h.packs.GetQueryEvents(params.Get("name"), sinceID, int(limit))
+	if err != nil {
+		log.Error("Failed to retrieve query events",
+			zap.Error(err))
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve query events")
+		return
+	}
+
+	nextSince := sinceID
+	events := make([]osquery.QueryEvent, 0, len(records))
+	for _, record := range records {
+		events = append(events, record.Event)
+		nextSince = record.ID
+	}
+
+	if params.Get("format") == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("X-Next-Since", strconv.FormatInt(nextSince, 10))
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				log.Error("Failed to write query event",
+					zap.Error(err))
+				return
+			}
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"events":     events,
+			"next_since": nextSince,
+		},
+	})
+
+	log.Info("Successfully responded with query events",
+		zap.Int("event_count", len(events)))
+}
+
+func (h *Handler) GetListeningPorts(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "listening ports", func(inventory database.InventoryStore) (interface{}, int, error) {
+		ports, err := inventory.GetLatestListeningPorts()
+		return ports, len(ports), err
+	})
+}
+
+// GetSudoAccounts lists the accounts sudoers grants access to, with when each
+// grant first and last appeared. ?all=true also returns grants since removed.
+func (h *Handler) GetSudoAccounts(w http.ResponseWriter, r *http.Request) {
+	includeGone := r.URL.Query().Get("all") == "true"
+	h.serveInventory(w, r, "sudo accounts", func(inventory database.InventoryStore) (interface{}, int, error) {
+		accounts, err := inventory.GetSudoAccounts(includeGone)
+		return accounts, len(accounts), err
+	})
+}
+
+func (h *Handler) GetAuthorizedKeys(w http.ResponseWriter, r *http.Request) {
+	includeGone := r.URL.Query().Get("all") == "true"
+	h.serveInventory(w, r, "authorized keys", func(inventory database.InventoryStore) (interface{}, int, error) {
+		keys, err := inventory.GetAuthorizedKeys(includeGone)
+		return keys, len(keys), err
+	})
+}
+
+// GetProcessTree returns the latest process snapshot as a parent/child tree.
+// ?sort=memory or ?sort=cpu orders each level largest first; the default is pid.
+func (h *Handler) GetProcessTree(w http.ResponseWriter, r *http.Request) {
+	sortBy := r.URL.Query().Get("sort")
+	h.serveInventory(w, r, "process tree", func(inventory database.InventoryStore) (interface{}, int, error) {
+		processes, err := inventory.GetLatestProcesses()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		tree := osquery.BuildProcessTree(processes)
+		osquery.SortProcessTree(tree, sortBy)
+		return tree, len(processes), nil
+	})
+}
+
+// GetPersistence returns the systemd units, cron jobs and startup items of the
+// latest snapshot. ?new=true returns only the entries flagged as new.
+func (h *Handler) GetPersistence(w http.ResponseWriter, r *http.Request) {
+	onlyNew := r.URL.Query().Get("new") == "true"
+	h.serveInventory(w, r, "persistence", func(inventory database.InventoryStore) (interface{}, int, error) {
+		persistence, err := inventory.GetLatestPersistence()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if onlyNew {
+			persistence = persistence.OnlyNew()
+		}
+		return persistence, len(persistence.SystemdUnits) + len(persistence.CronJobs) + len(persistence.StartupItems), nil
+	})
+}
+
+// GetExpiringCertificates lists certificates expiring within the configured
+// window, soonest first. ?within=<duration> overrides the window.
+func (h *Handler) GetExpiringCertificates(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "expiring certificates", func(inventory database.InventoryStore) (interface{}, int, error) {
+		within := h.options.CertificateExpiryWindow
+		if value := r.URL.Query().Get("within"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed < 0 {
+				return nil, 0, badRequestError("Invalid within parameter")
+			}
+			within = parsed
+		}
+
+		certificates, err := inventory.GetExpiringCertificates(within)
+		return certificates, len(certificates), err
+	})
+}
+
+// GetFileChanges pages through file integrity changes. Filter with
+// ?path=<prefix> and ?type=created|modified|deleted, page with since/limit.
+func (h *Handler) GetFileChanges(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestIDFromContext(r.Context())
+	log := logger.WithRequestID(requestID)
+
+	log.Info("Processing file changes request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed",
+			zap.String("method", r.Method))
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.inventory == nil {
+		respondWithError(w, http.StatusNotImplemented, notSupportedMessage)
+		return
+	}
+
+	params := r.URL.Query()
+	changeType := params.Get("type")
+	switch changeType {
+	case "", osquery.FileCreated, osquery.FileModified, osquery.FileDeleted:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid type parameter")
+		return
+	}
+	sinceID, err := parseInt64Param(params.Get("since"), 0)
+	if err != nil || sinceID < 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid since parameter")
+		return
+	}
+	limit, err := parseInt64Param(params.Get("limit"), defaultEventLimit)
+	if err != nil || limit <= 0 || limit > maxEventLimit {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+		return
+	}
+
+	changes, err := h.inventory.GetFileChanges(params.Get("path"), changeType, sinceID, int(limit))
+	if err != nil {
+		log.Error("Failed to retrieve file changes",
+			zap.Error(err))
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve file changes")
+		return
+	}
+
+	nextSince := sinceID
+	if len(changes) > 0 {
+		nextSince = changes[len(changes)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"changes":    changes,
+			"next_since": nextSince,
+		},
+	})
+
+	log.Info("Successfully responded with file changes",
+		zap.Int("change_count", len(changes)))
+}
+
+// GetMounts returns the mounts of the latest snapshot with their usage,
+// flagging those over the configured thresholds.
+func (h *Handler) GetMounts(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "mounts", func(inventory database.InventoryStore) (interface{}, int, error) {
+		mounts, err := inventory.GetLatestMounts()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		health := h.checkMounts(mounts)
+		return map[string]interface{}{
+			"mounts": mounts,
+			"health": health,
+		}, len(mounts), nil
+	})
+}
+
+// GetMountHistory returns usage over time on the latest snapshot's host,
+// grouped by mount path. Filter with ?path=<mount path> and choose how far
+// back with ?window=<duration>.
+func (h *Handler) GetMountHistory(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "mount history", func(inventory database.InventoryStore) (interface{}, int, error) {
+		window := defaultMountHistoryWindow
+		if value := r.URL.Query().Get("window"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return nil, 0, badRequestError("Invalid window parameter")
+			}
+			window = parsed
+		}
+
+		points, err := inventory.GetMountHistory(r.URL.Query().Get("path"), time.Now().Add(-window))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		history := map[string][]model.MountUsage{}
+		for _, point := range points {
+			history[point.Path] = append(history[point.Path], point)
+		}
+		return history, len(points), nil
+	})
+}
+
+// checkMounts marks the mounts over a threshold and reports the host as
+// degraded if there are any.
+func (h *Handler) checkMounts(mounts []model.MountUsage) model.HostHealth {
+	health := model.HostHealth{Status: model.HealthOK, Reasons: []string{}}
+	for i := range mounts {
+		mount := &mounts[i]
+		if h.options.DiskUsageThreshold > 0 && mount.UsedPercent >= h.options.DiskUsageThreshold {
+			mount.OverThreshold = true
+			health.Reasons = append(health.Reasons, fmt.Sprintf("%s is %.1f%% full (threshold %.0f%%)",
+				mount.Path, mount.UsedPercent, h.options.DiskUsageThreshold))
+		}
+		if h.options.InodeUsageThreshold > 0 && mount.InodesUsedPercent >= h.options.InodeUsageThreshold {
+			mount.OverThreshold = true
+			health.Reasons = append(health.Reasons, fmt.Sprintf("%s has %.1f%% of inodes used (threshold %.0f%%)",
+				mount.Path, mount.InodesUsedPercent, h.options.InodeUsageThreshold))
+		}
+	}
+
+	if len(health.Reasons) > 0 {
+		health.Status = model.HealthDegraded
+	}
+	return health
+}
+
+func (h *Handler) GetKernelModules(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "kernel modules", func(inventory database.InventoryStore) (interface{}, int, error) {
+		modules, err := inventory.GetLatestKernelModules()
+		return modules, len(modules), err
+	})
+}
+
+func (h *Handler) GetSystemControls(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "system controls", func(inventory database.InventoryStore) (interface{}, int, error) {
+		controls, err := inventory.GetLatestSystemControls()
+		return controls, len(controls), err
+	})
+}
+
+// GetSysctlDeviations reports, for the latest snapshot of every host, the
+// sysctls that differ from the configured baseline. A host is compliant when
+// it has none; the count is the number of hosts that are not. Hosts whose
+// sysctls were not collected are reported as such and keep the overall
+// result from being compliant, since nothing is known about them.
+func (h *Handler) GetSysctlDeviations(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "sysctl deviations", func(inventory database.InventoryStore) (interface{}, int, error) {
+		hosts, err := inventory.GetHostSystemControls()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		reports := make([]model.HostSysctlDeviations, 0, len(hosts))
+		nonCompliant, notCollected := 0, 0
+		for _, host := range hosts {
+			deviations := osquery.CheckSysctlBaseline(host.Controls, host.Collected, h.options.SysctlBaseline)
+			switch {
+			case !host.Collected:
+				notCollected++
+				deviations = []osquery.SysctlDeviation{}
+			case len(deviations) > 0:
+				nonCompliant++
+			}
+			reports = append(reports, model.HostSysctlDeviations{
+				Hostname:     host.Hostname,
+				HardwareUUID: host.HardwareUUID,
+				CollectedAt:  host.CollectedAt,
+				Collected:    host.Collected,
+				Compliant:    host.Collected && len(deviations) == 0,
+				Deviations:   deviations,
+			})
+		}
+
+		return map[string]interface{}{
+			"baseline_count":      len(h.options.SysctlBaseline),
+			"compliant":           nonCompliant == 0 && notCollected == 0,
+			"not_collected_count": notCollected,
+			"hosts":               reports,
+		}, nonCompliant, nil
+	})
+}
+
+func (h *Handler) GetContainers(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "containers", func(inventory database.InventoryStore) (interface{}, int, error) {
+		containers, err := inventory.GetLatestContainers()
+		return containers, len(containers), err
+	})
+}
+
+func (h *Handler) GetImages(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "images", func(inventory database.InventoryStore) (interface{}, int, error) {
+		images, err := inventory.GetLatestImages()
+		return images, len(images), err
+	})
+}
+
+func (h *Handler) GetExtensions(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "extensions", func(inventory database.InventoryStore) (interface{}, int, error) {
+		extensions, err := inventory.GetLatestExtensions()
+		return extensions, len(extensions), err
+	})
+}
+
+// FindExtensionInstalls lists every host and user whose latest snapshot has
+// an extension matching ?identifier=<exact id> or ?name=<substring>.
+func (h *Handler) FindExtensionInstalls(w http.ResponseWriter, r *http.Request) {
+	h.serveInventory(w, r, "extension installs", func(inventory database.InventoryStore) (interface{}, int, error) {
+		identifier := r.URL.Query().Get("identifier")
+		name := r.URL.Query().Get("name")
+		if identifier == "" && name == "" {
+			return nil, 0, badRequestError("identifier or name parameter is required")
+		}
+
+		installs, err := inventory.FindExtensionInstalls(identifier, name)
+		return installs, len(installs), err
+	})
+}
+
+// GetSnapshots lists the stored snapshots newest first, up to ?limit=<n>.
+func (h *Handler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.serveLatest(w, r, "snapshots", func() (interface{}, int, error) {
+		limit, err := parseInt64Param(r.URL.Query().Get("limit"), defaultSnapshotLimit)
+		if err != nil || limit <= 0 || limit > maxSnapshotLimit {
+			return nil, 0, badRequestError("Invalid limit parameter")
+		}
+
+		snapshots, err := h.dbService.ListSnapshots(int(limit))
+		return snapshots, len(snapshots), err
+	})
+}
+
+// DiffSnapshots compares the installed apps and listening ports of snapshot
+// ?from=<id> with ?to=<id>. to defaults to the latest snapshot and from to the
+// same host's one before to. Unknown snapshots, or a to with nothing before it, are 404.
+func (h *Handler) DiffSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.serveLatest(w, r, "snapshot diff", func() (interface{}, int, error) {
+		fromID, err := parseInt64Param(r.URL.Query().Get("from"), 0)
+		if err != nil || fromID < 0 {
+			return nil, 0, badRequestError("Invalid from parameter")
+		}
+		toID, err := parseInt64Param(r.URL.Query().Get("to"), 0)
+		if err != nil || toID < 0 {
+			return nil, 0, badRequestError("Invalid to parameter")
+		}
+
+		diff, err := h.dbService.DiffSnapshots(fromID, toID)
+		if errors.Is(err, database.ErrSnapshotNotFound) || errors.Is(err, database.ErrNoPreviousSnapshot) {
+			return nil, 0, notFoundError(err.Error())
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		count := len(diff.AppsAdded) + len(diff.AppsRemoved) + len(diff.AppsChanged) +
+			len(diff.PortsAdded) + len(diff.PortsRemoved)
+		return diff, count, nil
+	})
+}
+
+// badRequestError and notFoundError are returned by serveLatest fetch
+// functions to answer 400 or 404 with their message.
+type badRequestError string
+
+func (e badRequestError) Error() string { return string(e) }
+
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+
+// serveLatest handles the read-only endpoints that return one collection from
+// the latest snapshot. Parameters are checked inside fetch, after the method.
+func (h *Handler) serveLatest(w http.ResponseWriter, r *http.Request, resource string, fetch func() (interface{}, int, error)) {
+	requestID := middleware.GetRequestIDFromContext(r.Context())
+	log := logger.WithRequestID(requestID)
+
+	log.Info("Processing "+resource+" request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed",
+			zap.String("method", r.Method))
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	data, count, err := fetch()
+	var badRequest badRequestError
+	var notFound notFoundError
+	switch {
+	case errors.As(err, &badRequest):
+		respondWithError(w, http.StatusBadRequest, string(badRequest))
+		return
+	case errors.As(err, &notFound):
+		respondWithError(w, http.StatusNotFound, string(notFound))
+		return
+	case errors.Is(err, errNotSupported):
+		respondWithError(w, http.StatusNotImplemented, notSupportedMessage)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to retrieve "+resource,
+			zap.Error(err))
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve "+resource)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Response{
+		Success: true,
+		Data:    data,
+	})
+
+	log.Info("Successfully responded with "+resource,
+		zap.Int("count", count))
+}
+
+// serveInventory is serveLatest for the collections that only backends
+// implementing database.InventoryStore keep.
+func (h *Handler) serveInventory(w http.ResponseWriter, r *http.Request, resource string, fetch func(inventory database.InventoryStore) (interface{}, int, error)) {
+	h.serveLatest(w, r, resource, func() (interface{}, int, error) {
+		if h.inventory == nil {
+			return nil, 0, errNotSupported
+		}
+		return fetch(h.inventory)
+	})
+}
+
+func parseInt64Param(value string, defaultValue int64) (int64, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	logger.Log.Warn("Sending error response",
 		zap.Int("status_code", code),
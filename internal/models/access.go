@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type SudoAccountRecord struct {
+	osquery.SudoAccount
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Present   bool      `json:"present"`
+}
+
+type AuthorizedKeyRecord struct {
+	osquery.AuthorizedKey
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Present   bool      `json:"present"`
+}
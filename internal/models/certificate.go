@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type CertificateRecord struct {
+	osquery.Certificate
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+// ExtensionInstall is an extension found in a host's latest snapshot.
+type ExtensionInstall struct {
+	Hostname     string    `json:"hostname"`
+	HardwareUUID string    `json:"hardware_uuid"`
+	CollectedAt  time.Time `json:"collected_at"`
+	osquery.Extension
+}
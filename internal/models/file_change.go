@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type FileChangeRecord struct {
+	ID           int64     `json:"id"`
+	SystemInfoID int64     `json:"system_info_id"`
+	DetectedAt   time.Time `json:"detected_at"`
+	osquery.FileChange
+}
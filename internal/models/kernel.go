@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+// HostSystemControls are the sysctls in a host's latest snapshot. Collected
+// is false when the snapshot skipped the system_controls collector.
+type HostSystemControls struct {
+	Hostname     string                  `json:"hostname"`
+	HardwareUUID string                  `json:"hardware_uuid"`
+	CollectedAt  time.Time               `json:"collected_at"`
+	Collected    bool                    `json:"collected"`
+	Controls     []osquery.SystemControl `json:"controls"`
+}
+
+// HostSysctlDeviations are the sysctls in a host's latest snapshot that
+// differ from the baseline. The host is compliant when there are none, and
+// neither compliant nor listed with deviations when its sysctls were not
+// collected.
+type HostSysctlDeviations struct {
+	Hostname     string                    `json:"hostname"`
+	HardwareUUID string                    `json:"hardware_uuid"`
+	CollectedAt  time.Time                 `json:"collected_at"`
+	Collected    bool                      `json:"collected"`
+	Compliant    bool                      `json:"compliant"`
+	Deviations   []osquery.SysctlDeviation `json:"deviations"`
+}
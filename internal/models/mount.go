@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type MountUsage struct {
+	osquery.Mount
+	CollectedAt       time.Time `json:"collected_at"`
+	UsedPercent       float64   `json:"used_percent"`
+	InodesUsedPercent float64   `json:"inodes_used_percent"`
+	OverThreshold     bool      `json:"over_threshold"`
+}
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// HostHealth summarises whether the latest snapshot crossed any configured
+// threshold. Reasons lists each crossing in human readable form.
+type HostHealth struct {
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons"`
+}
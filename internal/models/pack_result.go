@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type PackResult struct {
+	ID             int           `json:"id"`
+	HostIdentifier string        `json:"host_identifier"`
+	PackName       string        `json:"pack"`
+	QueryName      string        `json:"query"`
+	Run            int64         `json:"run"`
+	Snapshot       bool          `json:"snapshot"`
+	RowCount       int           `json:"row_count"`
+	Rows           []osquery.Row `json:"rows"`
+	CollectedAt    time.Time     `json:"collected_at"`
+}
+
+type QueryEventRecord struct {
+	ID    int64
+	Event osquery.QueryEvent
+}
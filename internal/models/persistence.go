@@ -0,0 +1,56 @@
+package models
+
+import "github.com/Siddharth9890/osquery-mvp/internal/osquery"
+
+// Persistence is the latest snapshot's persistence mechanisms. New marks
+// entries absent from the previous snapshot; it is never set when there is no
+// previous snapshot to compare with or its collector was skipped.
+type Persistence struct {
+	SnapshotID         int64               `json:"snapshot_id"`
+	PreviousSnapshotID int64               `json:"previous_snapshot_id,omitempty"`
+	SystemdUnits       []SystemdUnitRecord `json:"systemd_units"`
+	CronJobs           []CronJobRecord     `json:"crontab"`
+	StartupItems       []StartupItemRecord `json:"startup_items"`
+}
+
+type SystemdUnitRecord struct {
+	osquery.SystemdUnit
+	New bool `json:"new"`
+}
+
+type CronJobRecord struct {
+	osquery.CronJob
+	New bool `json:"new"`
+}
+
+type StartupItemRecord struct {
+	osquery.StartupItem
+	New bool `json:"new"`
+}
+
+// OnlyNew returns a copy of p containing just the entries flagged as new.
+func (p Persistence) OnlyNew() Persistence {
+	filtered := Persistence{
+		SnapshotID:         p.SnapshotID,
+		PreviousSnapshotID: p.PreviousSnapshotID,
+		SystemdUnits:       []SystemdUnitRecord{},
+		CronJobs:           []CronJobRecord{},
+		StartupItems:       []StartupItemRecord{},
+	}
+	for _, unit := range p.SystemdUnits {
+		if unit.New {
+			filtered.SystemdUnits = append(filtered.SystemdUnits, unit)
+		}
+	}
+	for _, job := range p.CronJobs {
+		if job.New {
+			filtered.CronJobs = append(filtered.CronJobs, job)
+		}
+	}
+	for _, item := range p.StartupItems {
+		if item.New {
+			filtered.StartupItems = append(filtered.StartupItems, item)
+		}
+	}
+	return filtered
+}
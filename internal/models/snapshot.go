@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+)
+
+type SnapshotSummary struct {
+	ID             int64     `json:"id"`
+	Hostname       string    `json:"hostname"`
+	HardwareUUID   string    `json:"hardware_uuid"`
+	OSName         string    `json:"os_name"`
+	OSVersion      string    `json:"os_version"`
+	OsqueryVersion string    `json:"osquery_version"`
+	CollectedAt    time.Time `json:"collected_at"`
+}
+
+// AppVersionChange is an installed app present in both snapshots of a diff
+// under a different version.
+type AppVersionChange struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+}
+
+// SnapshotDiff lists what changed in installed apps and listening ports
+// between two stored snapshots.
+type SnapshotDiff struct {
+	From SnapshotSummary `json:"from"`
+	To   SnapshotSummary `json:"to"`
+
+	AppsAdded   []osquery.InstalledApp `json:"apps_added"`
+	AppsRemoved []osquery.InstalledApp `json:"apps_removed"`
+	AppsChanged []AppVersionChange     `json:"apps_changed"`
+
+	PortsAdded   []osquery.ListeningPort `json:"ports_added"`
+	PortsRemoved []osquery.ListeningPort `json:"ports_removed"`
+}
@@ -7,11 +7,35 @@ import (
 )
 
 type SystemInfo struct {
-	ID             int                    `json:"id"`
-	OSVersion      string                 `json:"os_version"`
-	OSName         string                 `json:"os_name"`
-	OSPlatform     string                 `json:"os_platform"`
-	OsqueryVersion string                 `json:"osquery_version"`
-	CollectedAt    time.Time              `json:"collected_at"`
-	Apps           []osquery.InstalledApp `json:"installed_apps"`
+	ID             int    `json:"id"`
+	OSVersion      string `json:"os_version"`
+	OSName         string `json:"os_name"`
+	OSPlatform     string `json:"os_platform"`
+	OsqueryVersion string `json:"osquery_version"`
+	OSBuild        string `json:"os_build"`
+	OSMajor        int    `json:"os_major"`
+	OSMinor        int    `json:"os_minor"`
+	OSArch         string `json:"os_arch"`
+
+	Hostname         string `json:"hostname"`
+	ComputerName     string `json:"computer_name"`
+	HardwareUUID     string `json:"hardware_uuid"`
+	CPUBrand         string `json:"cpu_brand"`
+	CPUPhysicalCores int    `json:"cpu_physical_cores"`
+	CPULogicalCores  int    `json:"cpu_logical_cores"`
+	PhysicalMemory   int64  `json:"physical_memory"`
+	HardwareVendor   string `json:"hardware_vendor"`
+	HardwareModel    string `json:"hardware_model"`
+	HardwareSerial   string `json:"hardware_serial"`
+	KernelVersion    string `json:"kernel_version"`
+	KernelArguments  string `json:"kernel_arguments"`
+	KernelPath       string `json:"kernel_path"`
+	UptimeSeconds    int64  `json:"uptime_seconds"`
+
+	CollectedAt time.Time              `json:"collected_at"`
+	Apps        []osquery.InstalledApp `json:"installed_apps"`
+
+	SkippedCollectors []osquery.SkippedCollector `json:"skipped_collectors"`
+
+	Health *HostHealth `json:"health,omitempty"`
 }
@@ -0,0 +1,325 @@
+package osquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type LocalUser struct {
+	UID         int64  `json:"uid" osquery:"uid"`
+	GID         int64  `json:"gid" osquery:"gid"`
+	Username    string `json:"username" osquery:"username"`
+	Description string `json:"description,omitempty" osquery:"description"`
+	Directory   string `json:"directory,omitempty" osquery:"directory"`
+	Shell       string `json:"shell,omitempty" osquery:"shell"`
+	UUID        string `json:"uuid,omitempty" osquery:"uuid"`
+}
+
+type LocalGroup struct {
+	GID       int64  `json:"gid" osquery:"gid"`
+	GroupName string `json:"groupname" osquery:"groupname"`
+}
+
+type UserGroup struct {
+	UID int64 `json:"uid" osquery:"uid"`
+	GID int64 `json:"gid" osquery:"gid"`
+}
+
+type LoggedInUser struct {
+	Type string `json:"type" osquery:"type"`
+	User string `json:"user" osquery:"user"`
+	TTY  string `json:"tty" osquery:"tty"`
+	Host string `json:"host,omitempty" osquery:"host"`
+	Time int64  `json:"time" osquery:"time"`
+	PID  int64  `json:"pid" osquery:"pid"`
+}
+
+type AuthorizedKey struct {
+	UID         int64  `json:"uid" osquery:"uid"`
+	Username    string `json:"username" osquery:"username"`
+	Algorithm   string `json:"algorithm" osquery:"algorithm"`
+	Key         string `json:"key" osquery:"key"`
+	KeyFile     string `json:"key_file" osquery:"key_file"`
+	Comment     string `json:"comment,omitempty" osquery:"comment"`
+	Fingerprint string `json:"fingerprint" osquery:"-"`
+}
+
+type SudoersRule struct {
+	Source      string `json:"source" osquery:"source"`
+	Header      string `json:"header" osquery:"header"`
+	RuleDetails string `json:"rule_details" osquery:"rule_details"`
+}
+
+type SudoAccount struct {
+	Username string `json:"username"`
+	UID      int64  `json:"uid"`
+	Via      string `json:"via"`
+	Source   string `json:"source"`
+	Rule     string `json:"rule"`
+}
+
+type Accounts struct {
+	Users          []LocalUser     `json:"users"`
+	Groups         []LocalGroup    `json:"groups"`
+	UserGroups     []UserGroup     `json:"user_groups"`
+	LoggedInUsers  []LoggedInUser  `json:"logged_in_users"`
+	AuthorizedKeys []AuthorizedKey `json:"authorized_keys"`
+	SudoersRules   []SudoersRule   `json:"sudoers"`
+	SudoAccounts   []SudoAccount   `json:"sudo_accounts"`
+}
+
+var (
+	usersQuery = tableQuery{
+		Table:    "users",
+		Columns:  []string{"uid", "gid", "username", "description", "directory", "shell", "uuid"},
+		Required: []string{"uid", "username"},
+	}
+	groupsQuery = tableQuery{
+		Table:    "groups",
+		Columns:  []string{"gid", "groupname"},
+		Required: []string{"gid", "groupname"},
+	}
+	userGroupsQuery = tableQuery{
+		Table:    "user_groups",
+		Columns:  []string{"uid", "gid"},
+		Required: []string{"uid", "gid"},
+	}
+	loggedInUsersQuery = tableQuery{
+		Table:   "logged_in_users",
+		Columns: []string{"type", "user", "tty", "host", "time", "pid"},
+	}
+	sudoersQuery = tableQuery{
+		Table:    "sudoers",
+		Columns:  []string{"source", "header", "rule_details"},
+		Required: []string{"header", "rule_details"},
+	}
+)
+
+func (c *OsqueryClient) GetAccounts(ctx context.Context, snapshot *Snapshot) (Accounts, error) {
+	var accounts Accounts
+
+	for _, collector := range []struct {
+		name  string
+		query tableQuery
+		dest  interface{}
+	}{
+		{"accounts/users", usersQuery, &accounts.Users},
+		{"accounts/groups", groupsQuery, &accounts.Groups},
+		{"accounts/user_groups", userGroupsQuery, &accounts.UserGroups},
+		{"accounts/logged_in_users", loggedInUsersQuery, &accounts.LoggedInUsers},
+		{"accounts/sudoers", sudoersQuery, &accounts.SudoersRules},
+	} {
+		reason, err := c.collectTable(ctx, collector.query, collector.dest)
+		if ctx.Err() != nil {
+			return accounts, ctx.Err()
+		}
+		snapshot.skip(ctx, collector.name, reason, err)
+	}
+
+	keys, reason, err := c.getAuthorizedKeys(ctx)
+	if ctx.Err() != nil {
+		return accounts, ctx.Err()
+	}
+	accounts.AuthorizedKeys = keys
+	snapshot.skip(ctx, "accounts/authorized_keys", reason, err)
+
+	accounts.SudoAccounts = ResolveSudoAccounts(accounts)
+	return accounts, nil
+}
+
+// authorized_keys has to be driven by a uid constraint, so it is joined
+// against users rather than selected on its own.
+func (c *OsqueryClient) getAuthorizedKeys(ctx context.Context) ([]AuthorizedKey, string, error) {
+	for _, table := range []string{"users", "authorized_keys"} {
+		if !c.HasTable(table) {
+			return nil, fmt.Sprintf("table %s is not available", table), nil
+		}
+	}
+
+	comment := "'' AS comment"
+	if c.HasColumn(ctx, "authorized_keys", "comment") {
+		comment = "ak.comment"
+	}
+
+	query := "SELECT u.uid, u.username, ak.algorithm, ak.key, ak.key_file, " + comment +
+		" FROM users u JOIN authorized_keys ak ON ak.uid = u.uid;"
+
+	result, err := c.Query(ctx, query)
+	if err != nil {
+		if isMissingSchemaError(err) {
+			return nil, err.Error(), nil
+		}
+		return nil, "", fmt.Errorf("failed to get authorized keys: %w", err)
+	}
+
+	var keys []AuthorizedKey
+	if err := result.Decode(&keys); err != nil {
+		return nil, "", fmt.Errorf("failed to decode authorized keys: %w", err)
+	}
+
+	for i := range keys {
+		keys[i].Fingerprint = KeyFingerprint(keys[i].Key)
+	}
+	return keys, "", nil
+}
+
+// KeyFingerprint returns the fingerprint ssh-keygen -l prints for a public
+// key: "SHA256:" and the unpadded base64 of the sha256 of the key blob. It is
+// empty when key is not valid base64.
+func KeyFingerprint(key string) string {
+	blob, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// ResolveSudoAccounts expands sudoers rules into the accounts they grant,
+// following "%group" headers through primary and supplementary membership
+// and User_Alias names through their members. Accounts granted through an
+// alias are reported with Via "alias:<name>". Defaults lines, alias
+// definitions and include directives grant nothing by themselves.
+func ResolveSudoAccounts(accounts Accounts) []SudoAccount {
+	usersByName := make(map[string]LocalUser, len(accounts.Users))
+	usersByUID := make(map[int64]LocalUser, len(accounts.Users))
+	for _, user := range accounts.Users {
+		usersByName[user.Username] = user
+		usersByUID[user.UID] = user
+	}
+
+	gidByName := make(map[string]int64, len(accounts.Groups))
+	for _, group := range accounts.Groups {
+		gidByName[group.GroupName] = group.GID
+	}
+
+	members := make(map[int64]map[int64]bool)
+	addMember := func(gid, uid int64) {
+		if members[gid] == nil {
+			members[gid] = make(map[int64]bool)
+		}
+		members[gid][uid] = true
+	}
+	for _, user := range accounts.Users {
+		addMember(user.GID, user.UID)
+	}
+	for _, membership := range accounts.UserGroups {
+		addMember(membership.GID, membership.UID)
+	}
+
+	userAliases := make(map[string][]string)
+	for _, rule := range accounts.SudoersRules {
+		if strings.TrimSpace(rule.Header) == "User_Alias" {
+			for name, aliasMembers := range parseSudoAliases(rule.RuleDetails) {
+				userAliases[name] = append(userAliases[name], aliasMembers...)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var result []SudoAccount
+	add := func(account SudoAccount) {
+		key := account.Username + "\x00" + account.Via
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, account)
+		}
+	}
+
+	// grant adds the accounts principal names. via is empty outside an alias,
+	// and expanding tracks the aliases being expanded so a loop ends.
+	var grant func(principal, via string, rule SudoersRule, expanding map[string]bool)
+	grant = func(principal, via string, rule SudoersRule, expanding map[string]bool) {
+		if principal == "" || strings.HasPrefix(principal, "!") {
+			return
+		}
+
+		if strings.HasPrefix(principal, "%") {
+			groupName := strings.TrimPrefix(principal, "%")
+			gid, ok := gidByName[groupName]
+			if !ok {
+				return
+			}
+			groupVia := via
+			if groupVia == "" {
+				groupVia = "group:" + groupName
+			}
+			for uid := range members[gid] {
+				user, ok := usersByUID[uid]
+				if !ok {
+					continue
+				}
+				add(SudoAccount{Username: user.Username, UID: uid, Via: groupVia, Source: rule.Source, Rule: rule.RuleDetails})
+			}
+			return
+		}
+
+		if aliasMembers, ok := userAliases[principal]; ok {
+			if expanding[principal] {
+				return
+			}
+			expanding[principal] = true
+			defer delete(expanding, principal)
+
+			aliasVia := via
+			if aliasVia == "" {
+				aliasVia = "alias:" + principal
+			}
+			for _, member := range aliasMembers {
+				grant(member, aliasVia, rule, expanding)
+			}
+			return
+		}
+
+		if user, ok := usersByName[principal]; ok {
+			userVia := via
+			if userVia == "" {
+				userVia = "user"
+			}
+			add(SudoAccount{Username: user.Username, UID: user.UID, Via: userVia, Source: rule.Source, Rule: rule.RuleDetails})
+		}
+	}
+
+	for _, rule := range accounts.SudoersRules {
+		header := strings.TrimSpace(rule.Header)
+		if header == "" || strings.HasPrefix(header, "Defaults") || strings.HasPrefix(header, "#") ||
+			strings.HasPrefix(header, "@") || strings.HasSuffix(header, "_Alias") {
+			continue
+		}
+
+		for _, principal := range strings.Split(header, ",") {
+			grant(strings.TrimSpace(principal), "", rule, make(map[string]bool))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Username != result[j].Username {
+			return result[i].Username < result[j].Username
+		}
+		return result[i].Via < result[j].Via
+	})
+	return result
+}
+
+// parseSudoAliases parses the body of an alias line, such as
+// "ADMINS = alice, %wheel : OPS = bob", into each alias's members.
+func parseSudoAliases(details string) map[string][]string {
+	aliases := make(map[string][]string)
+	for _, definition := range strings.Split(details, ":") {
+		name, list, ok := strings.Cut(definition, "=")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		for _, member := range strings.Split(list, ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				aliases[name] = append(aliases[name], member)
+			}
+		}
+	}
+	return aliases
+}
@@ -0,0 +1,61 @@
+package osquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyFingerprint(t *testing.T) {
+	// ssh-keygen -lf prints SHA256:t5JD55PWZ0NQ9wcsjT3DnMZNplxW1gkz/prTWi07veg for this key.
+	key := "AAAAC3NzaC1lZDI1NTE5AAAAIJI2a4X5cOkkLl2CTFeOSHy8NBNynYSSxMWwTUXDSypp"
+	if got, want := KeyFingerprint(key), "SHA256:t5JD55PWZ0NQ9wcsjT3DnMZNplxW1gkz/prTWi07veg"; got != want {
+		t.Errorf("KeyFingerprint = %q, want %q", got, want)
+	}
+	if got := KeyFingerprint("not base64!"); got != "" {
+		t.Errorf("KeyFingerprint of an invalid key = %q, want empty", got)
+	}
+}
+
+func TestResolveSudoAccounts(t *testing.T) {
+	accounts := Accounts{
+		Users: []LocalUser{
+			{UID: 0, GID: 0, Username: "root"},
+			{UID: 1000, GID: 1000, Username: "alice"},
+			{UID: 1001, GID: 1001, Username: "bob"},
+			{UID: 1002, GID: 27, Username: "carol"},
+			{UID: 1003, GID: 1003, Username: "dave"},
+			{UID: 1004, GID: 1004, Username: "erin"},
+		},
+		Groups: []LocalGroup{
+			{GID: 27, GroupName: "sudo"},
+			{GID: 1005, GroupName: "ops"},
+		},
+		UserGroups: []UserGroup{
+			{UID: 1001, GID: 27},
+			{UID: 1003, GID: 1005},
+		},
+		SudoersRules: []SudoersRule{
+			{Source: "/etc/sudoers", Header: "Defaults", RuleDetails: "env_reset"},
+			{Source: "/etc/sudoers", Header: "root", RuleDetails: "ALL=(ALL:ALL) ALL"},
+			{Source: "/etc/sudoers", Header: "%sudo", RuleDetails: "ALL=(ALL:ALL) ALL"},
+			{Source: "/etc/sudoers", Header: "@includedir", RuleDetails: "/etc/sudoers.d"},
+			{Source: "/etc/sudoers.d/admins", Header: "User_Alias", RuleDetails: "ADMINS = alice, %ops, LOOP : LOOP = ADMINS, erin"},
+			{Source: "/etc/sudoers.d/admins", Header: "Cmnd_Alias", RuleDetails: "REBOOT = /sbin/reboot"},
+			{Source: "/etc/sudoers.d/admins", Header: "ADMINS", RuleDetails: "ALL=(root) REBOOT"},
+			{Source: "/etc/sudoers.d/admins", Header: "nobody,alice", RuleDetails: "ALL=(ALL) NOPASSWD: ALL"},
+		},
+	}
+
+	want := []SudoAccount{
+		{Username: "alice", UID: 1000, Via: "alias:ADMINS", Source: "/etc/sudoers.d/admins", Rule: "ALL=(root) REBOOT"},
+		{Username: "alice", UID: 1000, Via: "user", Source: "/etc/sudoers.d/admins", Rule: "ALL=(ALL) NOPASSWD: ALL"},
+		{Username: "bob", UID: 1001, Via: "group:sudo", Source: "/etc/sudoers", Rule: "ALL=(ALL:ALL) ALL"},
+		{Username: "carol", UID: 1002, Via: "group:sudo", Source: "/etc/sudoers", Rule: "ALL=(ALL:ALL) ALL"},
+		{Username: "dave", UID: 1003, Via: "alias:ADMINS", Source: "/etc/sudoers.d/admins", Rule: "ALL=(root) REBOOT"},
+		{Username: "erin", UID: 1004, Via: "alias:ADMINS", Source: "/etc/sudoers.d/admins", Rule: "ALL=(root) REBOOT"},
+		{Username: "root", UID: 0, Via: "user", Source: "/etc/sudoers", Rule: "ALL=(ALL:ALL) ALL"},
+	}
+	if got := ResolveSudoAccounts(accounts); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveSudoAccounts =\n%+v\nwant\n%+v", got, want)
+	}
+}
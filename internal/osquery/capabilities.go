@@ -0,0 +1,171 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var selectAliasPattern = regexp.MustCompile(`(?i)^([a-z_][a-z0-9_]*)(?:\s+as\s+([a-z_][a-z0-9_]*))?$`)
+
+type SkippedCollector struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// tableQuery describes a single-table collector query that can be adapted to
+// the schema of the host: optional columns missing from the table are
+// replaced with empty literals, missing required columns skip the collector.
+type tableQuery struct {
+	Table       string
+	MinVersion  Version
+	Columns     []string
+	Required    []string
+	Where       string
+	WhereColumn string
+}
+
+// DetectVersion asks the backend which osquery version it is running so
+// collectors can be gated on it.
+func (c *OsqueryClient) DetectVersion(ctx context.Context) (Version, error) {
+	rows, err := c.querier.Query(ctx, "SELECT version FROM osquery_info;")
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to query osquery version: %w", err)
+	}
+	if len(rows) == 0 {
+		return Version{}, fmt.Errorf("no osquery version data returned")
+	}
+
+	version, err := ParseVersion(Row(rows[0]).String("version"))
+	if err != nil {
+		return Version{}, err
+	}
+
+	c.mu.Lock()
+	c.version = version
+	c.mu.Unlock()
+
+	return version, nil
+}
+
+func (c *OsqueryClient) OsqueryVersion() Version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// DiscoverTables loads the set of tables this osquery build provides. Until it
+// succeeds every table is assumed to exist.
+func (c *OsqueryClient) DiscoverTables(ctx context.Context) (int, error) {
+	rows, err := c.querier.Query(ctx, "SELECT name FROM osquery_registry WHERE registry = 'table' AND active = '1';")
+	if err != nil || len(rows) == 0 {
+		rows, err = c.querier.Query(ctx, "SELECT name FROM sqlite_master WHERE type = 'table';")
+		if err != nil {
+			return 0, fmt.Errorf("failed to discover osquery tables: %w", err)
+		}
+	}
+
+	tables := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if name, ok := row["name"].(string); ok && name != "" {
+			tables[name] = true
+		}
+	}
+
+	c.mu.Lock()
+	c.tables = tables
+	c.mu.Unlock()
+
+	for _, q := range []tableQuery{
+		osVersionQuery, systemInfoQuery, kernelInfoQuery, uptimeQuery,
+		usersQuery, groupsQuery, userGroupsQuery, loggedInUsersQuery, sudoersQuery,
+		processesQuery, systemdUnitsQuery, crontabQuery, startupItemsQuery, certificatesQuery,
+		mountsQuery, kernelModulesQuery,
+		dockerContainersQuery, dockerContainerPortsQuery, dockerContainerLabelsQuery, dockerImagesQuery,
+	} {
+		c.tableColumns(ctx, q.Table)
+	}
+	for _, source := range packageSources {
+		if source.supports(runtime.GOOS) {
+			c.tableColumns(ctx, source.Query.Table)
+		}
+	}
+
+	return len(tables), nil
+}
+
+func (c *OsqueryClient) HasTable(table string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.tables) == 0 {
+		return true
+	}
+	return c.tables[table]
+}
+
+func (c *OsqueryClient) HasColumn(ctx context.Context, table, column string) bool {
+	columns := c.tableColumns(ctx, table)
+	if len(columns) == 0 {
+		return true
+	}
+	for _, known := range columns {
+		if known.Name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// requireVersion returns why table cannot be queried when the detected
+// osquery version is older than min. An undetected version is not gated.
+func (c *OsqueryClient) requireVersion(table string, min Version) string {
+	if version := c.OsqueryVersion(); !version.IsZero() && !version.AtLeast(min) {
+		return fmt.Sprintf("table %s requires osquery %s, found %s", table, min, version)
+	}
+	return ""
+}
+
+func (c *OsqueryClient) buildQuery(ctx context.Context, q tableQuery) (string, string) {
+	if reason := c.requireVersion(q.Table, q.MinVersion); reason != "" {
+		return "", reason
+	}
+
+	if !c.HasTable(q.Table) {
+		return "", fmt.Sprintf("table %s is not available", q.Table)
+	}
+
+	for _, column := range q.Required {
+		if !c.HasColumn(ctx, q.Table, column) {
+			return "", fmt.Sprintf("table %s has no column %s", q.Table, column)
+		}
+	}
+
+	selects := make([]string, 0, len(q.Columns))
+	for _, expr := range q.Columns {
+		match := selectAliasPattern.FindStringSubmatch(strings.TrimSpace(expr))
+		if match == nil {
+			selects = append(selects, expr)
+			continue
+		}
+
+		column, alias := match[1], match[2]
+		if alias == "" {
+			alias = column
+		}
+		if c.HasColumn(ctx, q.Table, column) {
+			selects = append(selects, expr)
+		} else {
+			selects = append(selects, "'' AS "+alias)
+		}
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + q.Table
+	if q.Where != "" && (q.WhereColumn == "" || c.HasColumn(ctx, q.Table, q.WhereColumn)) {
+		query += " WHERE " + q.Where
+	}
+	return query + ";", ""
+}
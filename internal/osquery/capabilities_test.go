@@ -0,0 +1,143 @@
+package osquery
+
+import (
+	"context"
+	"testing"
+)
+
+func tableInfoRows(columns ...string) []map[string]interface{} {
+	rows := make([]map[string]interface{}, len(columns))
+	for i, column := range columns {
+		rows[i] = map[string]interface{}{"name": column, "type": "TEXT"}
+	}
+	return rows
+}
+
+var testPackagesQuery = tableQuery{
+	Table:       "deb_packages",
+	Columns:     []string{"name", "version", "maintainer AS vendor"},
+	Required:    []string{"name"},
+	Where:       "status LIKE '%installed'",
+	WhereColumn: "status",
+}
+
+func TestBuildQuery(t *testing.T) {
+	fake := NewFakeQuerier()
+	fake.SetResult("PRAGMA table_info(deb_packages);", tableInfoRows("name", "version", "status"))
+	fake.SetResult("PRAGMA table_info(rpm_packages);", tableInfoRows("name", "version"))
+	fake.SetResult("PRAGMA table_info(apk_packages);", tableInfoRows("version"))
+	c := NewOsqueryClient(fake)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		q      tableQuery
+		query  string
+		reason string
+	}{
+		{
+			name:  "missing aliased column becomes an empty literal",
+			q:     testPackagesQuery,
+			query: "SELECT name, version, '' AS vendor FROM deb_packages WHERE status LIKE '%installed';",
+		},
+		{
+			name: "missing where column drops the where clause",
+			q: tableQuery{
+				Table:       "rpm_packages",
+				Columns:     []string{"name", "version", "arch"},
+				Where:       "status = 'installed'",
+				WhereColumn: "status",
+			},
+			query: "SELECT name, version, '' AS arch FROM rpm_packages;",
+		},
+		{
+			name:   "missing required column skips the table",
+			q:      tableQuery{Table: "apk_packages", Columns: []string{"name", "version"}, Required: []string{"name"}},
+			reason: "table apk_packages has no column name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, reason := c.buildQuery(ctx, tt.q)
+			if query != tt.query || reason != tt.reason {
+				t.Fatalf("buildQuery = (%q, %q), want (%q, %q)", query, reason, tt.query, tt.reason)
+			}
+		})
+	}
+}
+
+func TestBuildQueryUnknownColumnsKeepEveryExpression(t *testing.T) {
+	fake := NewFakeQuerier()
+	fake.SetError("PRAGMA table_info(deb_packages);", &ParseError{Query: "PRAGMA table_info(deb_packages);"})
+	c := NewOsqueryClient(fake)
+
+	query, reason := c.buildQuery(context.Background(), testPackagesQuery)
+	want := "SELECT name, version, maintainer AS vendor FROM deb_packages WHERE status LIKE '%installed';"
+	if query != want || reason != "" {
+		t.Fatalf("buildQuery = (%q, %q), want (%q, \"\")", query, reason, want)
+	}
+}
+
+func TestDiscoverTables(t *testing.T) {
+	fake := NewFakeQuerier()
+	fake.SetResult("SELECT name FROM osquery_registry WHERE registry = 'table' AND active = '1';", nil)
+	fake.SetResult("SELECT name FROM sqlite_master WHERE type = 'table';", []map[string]interface{}{
+		{"name": "os_version"}, {"name": "deb_packages"},
+	})
+	c := NewOsqueryClient(fake)
+
+	if !c.HasTable("rpm_packages") {
+		t.Fatal("HasTable before discovery = false, want every table assumed present")
+	}
+
+	count, err := c.DiscoverTables(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("DiscoverTables = %d, %v; want 2 tables", count, err)
+	}
+	if !c.HasTable("deb_packages") || c.HasTable("rpm_packages") {
+		t.Fatal("HasTable does not follow the discovered tables")
+	}
+
+	if query, reason := c.buildQuery(context.Background(), tableQuery{Table: "rpm_packages", Columns: []string{"name"}}); reason != "table rpm_packages is not available" {
+		t.Fatalf("buildQuery = (%q, %q), want the missing table skipped", query, reason)
+	}
+}
+
+func TestBuildQueryVersionGate(t *testing.T) {
+	c := NewOsqueryClient(NewFakeQuerier())
+	c.tableInfo["docker_containers"] = []Column{{Name: "id"}, {Name: "name"}, {Name: "image"}}
+	ctx := context.Background()
+
+	if _, reason := c.buildQuery(ctx, dockerContainersQuery); reason != "" {
+		t.Fatalf("undetected version gated the table: %q", reason)
+	}
+
+	c.version = Version{Major: 2, Minor: 8}
+	want := "table docker_containers requires osquery 2.9.0, found 2.8.0"
+	if _, reason := c.buildQuery(ctx, dockerContainersQuery); reason != want {
+		t.Fatalf("old version reason = %q, want %q", reason, want)
+	}
+
+	c.version = Version{Major: 5, Minor: 11}
+	if query, reason := c.buildQuery(ctx, dockerContainersQuery); reason != "" || query == "" {
+		t.Fatalf("buildQuery = (%q, %q), want a query", query, reason)
+	}
+}
+
+func TestQueryExtensionSourceVersionGate(t *testing.T) {
+	c := NewOsqueryClient(NewFakeQuerier())
+	c.version = Version{Major: 5, Minor: 2}
+
+	for _, source := range extensionSources {
+		if source.Table != "vscode_extensions" {
+			continue
+		}
+		_, reason, err := c.queryExtensionSource(context.Background(), source)
+		if err != nil || reason != "table vscode_extensions requires osquery 5.3.0, found 5.2.0" {
+			t.Fatalf("queryExtensionSource = %q, %v", reason, err)
+		}
+		return
+	}
+	t.Fatal("no vscode_extensions source")
+}
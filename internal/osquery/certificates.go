@@ -0,0 +1,96 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Certificate struct {
+	CommonName     string `json:"common_name" osquery:"common_name"`
+	Subject        string `json:"subject" osquery:"subject"`
+	Issuer         string `json:"issuer" osquery:"issuer"`
+	Serial         string `json:"serial" osquery:"serial"`
+	SHA1           string `json:"sha1" osquery:"sha1"`
+	NotValidBefore int64  `json:"not_valid_before" osquery:"not_valid_before"`
+	NotValidAfter  int64  `json:"not_valid_after" osquery:"not_valid_after"`
+	CA             bool   `json:"ca" osquery:"ca"`
+	Path           string `json:"path" osquery:"path"`
+}
+
+var certificatesQuery = tableQuery{
+	Table: "certificates",
+	Columns: []string{
+		"common_name", "subject", "issuer", "serial", "sha1",
+		"not_valid_before", "not_valid_after", "ca", "path",
+	},
+	Required: []string{"sha1", "not_valid_after"},
+}
+
+// SetCertificateDirs adds directories whose PEM files are read through the
+// certificates table on top of the platform's default stores.
+func (c *OsqueryClient) SetCertificateDirs(dirs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.certificateDirs = dirs
+}
+
+func (c *OsqueryClient) GetCertificates(ctx context.Context, snapshot *Snapshot) ([]Certificate, error) {
+	c.mu.Lock()
+	dirs := c.certificateDirs
+	c.mu.Unlock()
+
+	var certificates []Certificate
+	seen := make(map[string]bool)
+	add := func(found []Certificate) {
+		for _, certificate := range found {
+			key := certificate.SHA1 + "\x00" + certificate.Path
+			if !seen[key] {
+				seen[key] = true
+				certificates = append(certificates, certificate)
+			}
+		}
+	}
+
+	var found []Certificate
+	reason, err := c.collectTable(ctx, certificatesQuery, &found)
+	if ctx.Err() != nil {
+		return certificates, ctx.Err()
+	}
+	snapshot.skip(ctx, "certificates", reason, err)
+	add(found)
+
+	for _, dir := range dirs {
+		q := certificatesQuery
+		q.Where = dirFilesWhere(dir)
+		q.WhereColumn = "path"
+
+		found = nil
+		reason, err := c.collectTable(ctx, q, &found)
+		if ctx.Err() != nil {
+			return certificates, ctx.Err()
+		}
+		snapshot.skip(ctx, "certificates/"+dir, reason, err)
+		add(found)
+	}
+
+	return certificates, nil
+}
+
+// dirFilesWhere matches the files directly inside dir. A "%" or "_" in dir
+// itself is escaped; the ESCAPE clause is only added then, since osquery
+// passes plain LIKE constraints on to the table to pick the files to read.
+func dirFilesWhere(dir string) string {
+	prefix := strings.TrimSuffix(filepath.Join(dir, "%"), "%")
+	if !strings.ContainsAny(prefix, "%_") {
+		return "path LIKE " + sqlString(prefix+"%")
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	return "path LIKE " + sqlString(escaped+"%") + ` ESCAPE '\'`
+}
+
+// sqlString quotes s as an SQLite string literal.
+func sqlString(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
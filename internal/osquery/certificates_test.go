@@ -0,0 +1,20 @@
+//go:build !windows
+
+package osquery
+
+import "testing"
+
+func TestDirFilesWhere(t *testing.T) {
+	tests := map[string]string{
+		"/etc/pki/custom":  `path LIKE '/etc/pki/custom/%'`,
+		"/etc/pki/custom/": `path LIKE '/etc/pki/custom/%'`,
+		"/opt/o'brien":     `path LIKE '/opt/o''brien/%'`,
+		"/opt/ca_certs":    `path LIKE '/opt/ca\_certs/%' ESCAPE '\'`,
+		`/opt/100%\certs`:  `path LIKE '/opt/100\%\\certs/%' ESCAPE '\'`,
+	}
+	for dir, want := range tests {
+		if got := dirFilesWhere(dir); got != want {
+			t.Errorf("dirFilesWhere(%q) = %s, want %s", dir, got, want)
+		}
+	}
+}
@@ -1,131 +1,184 @@
 package osquery
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os/exec"
-	"runtime"
-	"strings"
+	"sync"
 )
 
 type OsqueryClient struct {
-	binaryPath string
+	querier Querier
+
+	mu        sync.Mutex
+	version   Version
+	tables    map[string]bool
+	tableInfo map[string][]Column
+
+	certificateDirs []string
+	fimPaths        []string
+	sysctlBaseline  SysctlBaseline
+
+	collectExtensions bool
 }
 
 type InstalledApp struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name        string `json:"name" osquery:"name"`
+	Version     string `json:"version" osquery:"version"`
+	Source      string `json:"source" osquery:"-"`
+	Arch        string `json:"arch,omitempty" osquery:"arch"`
+	Vendor      string `json:"vendor,omitempty" osquery:"vendor"`
+	InstallTime int64  `json:"install_time,omitempty" osquery:"install_time"`
 }
 
-func NewOsqueryClient() *OsqueryClient {
+func NewOsqueryClient(querier Querier) *OsqueryClient {
 	return &OsqueryClient{
-		binaryPath: "osqueryi",
+		querier:   querier,
+		tableInfo: make(map[string][]Column),
 	}
 }
 
 type SystemInfoResult struct {
-	OSVersion      string
-	OSName         string
-	OSPlatform     string
-	OsqueryVersion string
+	OSVersion      string `osquery:"version"`
+	OSName         string `osquery:"name"`
+	OSPlatform     string `osquery:"platform"`
+	OSBuild        string `osquery:"build"`
+	OSMajor        int    `osquery:"major"`
+	OSMinor        int    `osquery:"minor"`
+	OSArch         string `osquery:"arch"`
+	OsqueryVersion string `osquery:"-"`
+
+	Hostname         string `osquery:"hostname"`
+	ComputerName     string `osquery:"computer_name"`
+	HardwareUUID     string `osquery:"uuid"`
+	CPUBrand         string `osquery:"cpu_brand"`
+	CPUPhysicalCores int    `osquery:"cpu_physical_cores"`
+	CPULogicalCores  int    `osquery:"cpu_logical_cores"`
+	PhysicalMemory   int64  `osquery:"physical_memory"`
+	HardwareVendor   string `osquery:"hardware_vendor"`
+	HardwareModel    string `osquery:"hardware_model"`
+	HardwareSerial   string `osquery:"hardware_serial"`
+
+	KernelVersion   string `osquery:"-"`
+	KernelArguments string `osquery:"-"`
+	KernelPath      string `osquery:"-"`
+	UptimeSeconds   int64  `osquery:"-"`
 }
 
-func (c *OsqueryClient) GetSystemInfo() (SystemInfoResult, error) {
+var (
+	osVersionQuery = tableQuery{
+		Table:    "os_version",
+		Columns:  []string{"version", "name", "platform", "build", "major", "minor", "arch"},
+		Required: []string{"version", "name", "platform"},
+	}
+	systemInfoQuery = tableQuery{
+		Table: "system_info",
+		Columns: []string{
+			"hostname", "computer_name", "uuid", "cpu_brand", "cpu_physical_cores", "cpu_logical_cores",
+			"physical_memory", "hardware_vendor", "hardware_model", "hardware_serial",
+		},
+		Required: []string{"hostname", "uuid"},
+	}
+	kernelInfoQuery = tableQuery{
+		Table:   "kernel_info",
+		Columns: []string{"version", "arguments", "path"},
+	}
+	uptimeQuery = tableQuery{
+		Table:   "uptime",
+		Columns: []string{"total_seconds"},
+	}
+)
+
+func (c *OsqueryClient) GetSystemInfo(ctx context.Context) (SystemInfoResult, error) {
 	result := SystemInfoResult{}
 
-	osQuery := "SELECT version, name, platform FROM os_version;"
-	osResult, err := c.executeQuery(osQuery)
+	osData, err := c.queryFirstRow(ctx, osVersionQuery)
 	if err != nil {
 		return result, fmt.Errorf("failed to get OS details: %w", err)
 	}
+	if osData == nil {
+		return result, fmt.Errorf("no OS data returned")
+	}
 
-	var osData []map[string]interface{}
-	if err := json.Unmarshal([]byte(osResult), &osData); err != nil {
-		return result, fmt.Errorf("failed to parse OS data: %w", err)
+	if err := osData.Decode(&result); err != nil {
+		return result, fmt.Errorf("failed to decode OS data: %w", err)
 	}
 
-	if len(osData) == 0 {
-		return result, fmt.Errorf("no OS data returned")
+	hostData, err := c.queryFirstRow(ctx, systemInfoQuery)
+	if err != nil {
+		return result, fmt.Errorf("failed to get host details: %w", err)
+	}
+	if hostData == nil {
+		return result, fmt.Errorf("no host data returned")
 	}
 
-	result.OSVersion = fmt.Sprintf("%v", osData[0]["version"])
-	result.OSName = fmt.Sprintf("%v", osData[0]["name"])
-	result.OSPlatform = fmt.Sprintf("%v", osData[0]["platform"])
+	if err := hostData.Decode(&result); err != nil {
+		return result, fmt.Errorf("failed to decode host data: %w", err)
+	}
 
-	osqueryVersionQuery := "SELECT version FROM osquery_info;"
-	osqueryVersionResult, err := c.executeQuery(osqueryVersionQuery)
+	kernelData, err := c.queryFirstRow(ctx, kernelInfoQuery)
 	if err != nil {
-		return result, fmt.Errorf("failed to get osquery version: %w", err)
+		return result, fmt.Errorf("failed to get kernel details: %w", err)
+	}
+	result.KernelVersion = kernelData.String("version")
+	result.KernelArguments = kernelData.String("arguments")
+	result.KernelPath = kernelData.String("path")
+
+	uptimeData, err := c.queryFirstRow(ctx, uptimeQuery)
+	if err != nil {
+		return result, fmt.Errorf("failed to get uptime: %w", err)
 	}
+	result.UptimeSeconds = uptimeData.Int64("total_seconds")
 
-	var osqueryVersionData []map[string]interface{}
-	if err := json.Unmarshal([]byte(osqueryVersionResult), &osqueryVersionData); err != nil {
-		return result, fmt.Errorf("failed to parse osquery version data: %w", err)
+	osqueryVersionQuery := "SELECT version FROM osquery_info;"
+	osqueryVersionData, err := c.Query(ctx, osqueryVersionQuery)
+	if err != nil {
+		return result, fmt.Errorf("failed to get osquery version: %w", err)
 	}
 
-	if len(osqueryVersionData) == 0 {
+	if len(osqueryVersionData.Rows) == 0 {
 		return result, fmt.Errorf("no osquery version data returned")
 	}
 
-	result.OsqueryVersion = fmt.Sprintf("%v", osqueryVersionData[0]["version"])
+	result.OsqueryVersion = osqueryVersionData.Rows[0].String("version")
 
 	return result, nil
 }
 
-func (c *OsqueryClient) GetInstalledApps() ([]InstalledApp, error) {
-	var query string
-
-	switch runtime.GOOS {
-	case "darwin":
-		query = "SELECT name, bundle_version AS version FROM apps LIMIT 100;"
-	case "windows":
-		query = "SELECT name, version FROM programs LIMIT 100;"
-	case "linux":
-		query = "SELECT name, version FROM deb_packages LIMIT 100;" +
-			" UNION SELECT name, version FROM rpm_packages LIMIT 100;"
-	default:
-		query = "SELECT DISTINCT name, path as version FROM processes LIMIT 100;"
-	}
-
-	result, err := c.executeQuery(query)
+// GetHardwareUUID returns system_info.uuid, the identifier snapshots of this
+// host are stored under.
+func (c *OsqueryClient) GetHardwareUUID(ctx context.Context) (string, error) {
+	hostData, err := c.queryFirstRow(ctx, tableQuery{
+		Table:    "system_info",
+		Columns:  []string{"uuid"},
+		Required: []string{"uuid"},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get installed apps: %w", err)
+		return "", fmt.Errorf("failed to get hardware uuid: %w", err)
 	}
-
-	var appsData []map[string]interface{}
-	if err := json.Unmarshal([]byte(result), &appsData); err != nil {
-		return nil, fmt.Errorf("failed to parse installed apps data: %w", err)
+	if uuid := hostData.String("uuid"); uuid != "" {
+		return uuid, nil
 	}
+	return "", fmt.Errorf("no hardware uuid returned")
+}
 
-	apps := make([]InstalledApp, 0, len(appsData))
-	for _, app := range appsData {
-		name, nameOk := app["name"].(string)
-		version, versionOk := app["version"].(string)
-
-		if !nameOk {
-			continue
-		}
-
-		if !versionOk {
-			version = "unknown"
-		}
-
-		apps = append(apps, InstalledApp{
-			Name:    name,
-			Version: version,
-		})
+// queryFirstRow runs a single-row table query. It returns a nil row when the
+// host cannot provide the table, so optional details are simply left empty.
+func (c *OsqueryClient) queryFirstRow(ctx context.Context, q tableQuery) (Row, error) {
+	query, reason := c.buildQuery(ctx, q)
+	if reason != "" {
+		return nil, nil
 	}
 
-	return apps, nil
-}
-
-func (c *OsqueryClient) executeQuery(query string) (string, error) {
-	cmd := exec.Command(c.binaryPath, "--json", query)
-	output, err := cmd.CombinedOutput()
+	result, err := c.Query(ctx, query)
 	if err != nil {
-		return "", fmt.Errorf("osquery execution failed: %w, output: %s", err, string(output))
+		if isMissingSchemaError(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	result := strings.TrimSpace(string(output))
-	return result, nil
+	if len(result.Rows) == 0 {
+		return nil, nil
+	}
+	return result.Rows[0], nil
 }
@@ -0,0 +1,139 @@
+package osquery
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decode fills dest, a pointer to a slice of structs, from the result rows.
+// Fields are matched by their `osquery:"column"` tag, falling back to the
+// lower-cased field name; a tag of "-" skips the field.
+func (rs ResultSet) Decode(dest interface{}) error {
+	ptr := reflect.ValueOf(dest)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("decode destination must be a pointer to a slice, got %T", dest)
+	}
+
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Pointer
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("decode destination must hold structs, got %s", elemType)
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(rs.Rows))
+	for i, row := range rs.Rows {
+		item := reflect.New(elemType)
+		if err := decodeRow(row, item.Elem()); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		if isPtr {
+			result = reflect.Append(result, item)
+		} else {
+			result = reflect.Append(result, item.Elem())
+		}
+	}
+
+	slice.Set(result)
+	return nil
+}
+
+func (r Row) Decode(dest interface{}) error {
+	ptr := reflect.ValueOf(dest)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode destination must be a pointer to a struct, got %T", dest)
+	}
+	return decodeRow(r, ptr.Elem())
+}
+
+func decodeRow(row Row, target reflect.Value) error {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		column := field.Tag.Get("osquery")
+		if column == "-" {
+			continue
+		}
+		if column == "" {
+			column = strings.ToLower(field.Name)
+		}
+
+		value, ok := row[column]
+		if !ok || value == nil {
+			continue
+		}
+
+		if err := assignValue(target.Field(i), value); err != nil {
+			return fmt.Errorf("column %q into field %s: %w", column, field.Name, err)
+		}
+	}
+	return nil
+}
+
+func assignValue(field reflect.Value, value interface{}) error {
+	text := Row{"v": value}.String("v")
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if text == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if text == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(n) {
+			return fmt.Errorf("value %d overflows %s", n, field.Type())
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if text == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		switch strings.ToLower(text) {
+		case "1", "true", "yes":
+			field.SetBool(true)
+		case "", "0", "false", "no":
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		field.SetBytes([]byte(text))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package osquery
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	ActionAdded   = "added"
+	ActionRemoved = "removed"
+
+	calendarTimeLayout = "Mon Jan _2 15:04:05 2006 UTC"
+)
+
+// QueryEvent mirrors a line of osqueryd's differential result log in event
+// format, so downstream consumers can ingest it unchanged.
+type QueryEvent struct {
+	Name           string            `json:"name"`
+	HostIdentifier string            `json:"hostIdentifier"`
+	CalendarTime   string            `json:"calendarTime"`
+	UnixTime       int64             `json:"unixTime"`
+	Epoch          int64             `json:"epoch"`
+	Counter        int64             `json:"counter"`
+	Numerics       bool              `json:"numerics"`
+	Columns        map[string]string `json:"columns"`
+	Action         string            `json:"action"`
+}
+
+type DiffResults struct {
+	Added   []map[string]string `json:"added"`
+	Removed []map[string]string `json:"removed"`
+}
+
+// PackQueryName is the name osqueryd logs pack query results under.
+func PackQueryName(pack, query string) string {
+	return "pack_" + pack + "_" + query
+}
+
+// StringColumns renders a row the way osquery logs it without numerics.
+func StringColumns(row Row) map[string]string {
+	columns := make(map[string]string, len(row))
+	for name := range row {
+		columns[name] = row.String(name)
+	}
+	return columns
+}
+
+// DiffRows compares two runs of a query as multisets of rows, like osqueryd
+// does for scheduled queries.
+func DiffRows(previous, current []Row) DiffResults {
+	counts := make(map[string]int)
+	for _, row := range previous {
+		counts[rowKey(row)]++
+	}
+
+	diff := DiffResults{
+		Added:   []map[string]string{},
+		Removed: []map[string]string{},
+	}
+	for _, row := range current {
+		key := rowKey(row)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		diff.Added = append(diff.Added, StringColumns(row))
+	}
+
+	for _, row := range previous {
+		key := rowKey(row)
+		if counts[key] > 0 {
+			counts[key]--
+			diff.Removed = append(diff.Removed, StringColumns(row))
+		}
+	}
+
+	return diff
+}
+
+func CalendarTime(unixTime int64) string {
+	return time.Unix(unixTime, 0).UTC().Format(calendarTimeLayout)
+}
+
+func (d DiffResults) Events(name, hostIdentifier string, counter int64, at time.Time, logRemoved bool) []QueryEvent {
+	base := QueryEvent{
+		Name:           name,
+		HostIdentifier: hostIdentifier,
+		CalendarTime:   CalendarTime(at.Unix()),
+		UnixTime:       at.Unix(),
+		Counter:        counter,
+	}
+
+	events := make([]QueryEvent, 0, len(d.Added)+len(d.Removed))
+	if logRemoved {
+		for _, columns := range d.Removed {
+			event := base
+			event.Columns = columns
+			event.Action = ActionRemoved
+			events = append(events, event)
+		}
+	}
+	for _, columns := range d.Added {
+		event := base
+		event.Columns = columns
+		event.Action = ActionAdded
+		events = append(events, event)
+	}
+	return events
+}
+
+func rowKey(row Row) string {
+	columns := StringColumns(row)
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		pairs = append(pairs, name, columns[name])
+	}
+	key, _ := json.Marshal(pairs)
+	return string(key)
+}
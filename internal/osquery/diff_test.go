@@ -0,0 +1,47 @@
+package osquery
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRows(t *testing.T) {
+	previous := []Row{
+		{"pid": int64(1), "name": "init"},
+		{"pid": int64(2), "name": "sshd"},
+		{"pid": int64(2), "name": "sshd"},
+		{"pid": int64(3), "name": "cron"},
+	}
+	current := []Row{
+		{"pid": json.Number("2"), "name": "sshd"},
+		{"pid": "1", "name": "init"},
+		{"pid": int64(4), "name": "nginx"},
+		{"pid": int64(4), "name": "nginx"},
+	}
+
+	diff := DiffRows(previous, current)
+
+	want := DiffResults{
+		Added: []map[string]string{
+			{"pid": "4", "name": "nginx"},
+			{"pid": "4", "name": "nginx"},
+		},
+		Removed: []map[string]string{
+			{"pid": "2", "name": "sshd"},
+			{"pid": "3", "name": "cron"},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("DiffRows = %+v, want %+v", diff, want)
+	}
+}
+
+func TestDiffRowsStoredNumbers(t *testing.T) {
+	stored := []Row{{"inode": json.Number("9007199254740993"), "size": int64(10)}}
+
+	diff := DiffRows(stored, []Row{{"inode": "9007199254740993", "size": "10"}})
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Fatalf("DiffRows = %+v, want no changes", diff)
+	}
+}
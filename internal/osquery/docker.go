@@ -0,0 +1,149 @@
+package osquery
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+type Container struct {
+	ID         string            `json:"id" osquery:"id"`
+	Name       string            `json:"name" osquery:"name"`
+	Image      string            `json:"image" osquery:"image"`
+	ImageID    string            `json:"image_id" osquery:"image_id"`
+	Command    string            `json:"command" osquery:"command"`
+	Created    int64             `json:"created" osquery:"created"`
+	State      string            `json:"state" osquery:"state"`
+	Status     string            `json:"status" osquery:"status"`
+	Privileged bool              `json:"privileged" osquery:"privileged"`
+	Ports      []ContainerPort   `json:"ports" osquery:"-"`
+	Labels     map[string]string `json:"labels" osquery:"-"`
+}
+
+type ContainerPort struct {
+	ContainerID string `json:"-" osquery:"id"`
+	Type        string `json:"type" osquery:"type"`
+	Port        int    `json:"port" osquery:"port"`
+	HostIP      string `json:"host_ip,omitempty" osquery:"host_ip"`
+	HostPort    int    `json:"host_port,omitempty" osquery:"host_port"`
+}
+
+type containerLabel struct {
+	ContainerID string `osquery:"id"`
+	Key         string `osquery:"key"`
+	Value       string `osquery:"value"`
+}
+
+type DockerImage struct {
+	ID        string   `json:"id" osquery:"id"`
+	Created   int64    `json:"created" osquery:"created"`
+	SizeBytes int64    `json:"size_bytes" osquery:"size_bytes"`
+	Tags      []string `json:"tags" osquery:"-"`
+	RawTags   string   `json:"-" osquery:"tags"`
+}
+
+type Docker struct {
+	Containers []Container   `json:"containers"`
+	Images     []DockerImage `json:"images"`
+}
+
+var (
+	// dockerMinVersion is the release that added the docker_* tables.
+	dockerMinVersion = Version{Major: 2, Minor: 9}
+
+	dockerContainersQuery = tableQuery{
+		Table:      "docker_containers",
+		MinVersion: dockerMinVersion,
+		Columns: []string{
+			"id", "name", "image", "image_id", "command", "created", "state", "status", "privileged",
+		},
+		Required: []string{"id", "image"},
+	}
+	dockerContainerPortsQuery = tableQuery{
+		Table:      "docker_container_ports",
+		MinVersion: dockerMinVersion,
+		Columns:    []string{"id", "type", "port", "host_ip", "host_port"},
+		Required:   []string{"id", "port"},
+	}
+	dockerContainerLabelsQuery = tableQuery{
+		Table:      "docker_container_labels",
+		MinVersion: dockerMinVersion,
+		Columns:    []string{"id", "key", "value"},
+		Required:   []string{"id", "key"},
+	}
+	dockerImagesQuery = tableQuery{
+		Table:      "docker_images",
+		MinVersion: dockerMinVersion,
+		Columns:    []string{"id", "created", "size_bytes", "tags"},
+		Required:   []string{"id"},
+	}
+)
+
+// GetDocker collects containers with their ports and labels, and images.
+// Hosts without Docker simply return no rows, so only missing tables are
+// recorded as skipped.
+func (c *OsqueryClient) GetDocker(ctx context.Context, snapshot *Snapshot) (Docker, error) {
+	var docker Docker
+	var ports []ContainerPort
+	var labels []containerLabel
+
+	for _, collector := range []struct {
+		name  string
+		query tableQuery
+		dest  interface{}
+	}{
+		{"docker/containers", dockerContainersQuery, &docker.Containers},
+		{"docker/container_ports", dockerContainerPortsQuery, &ports},
+		{"docker/container_labels", dockerContainerLabelsQuery, &labels},
+		{"docker/images", dockerImagesQuery, &docker.Images},
+	} {
+		reason, err := c.collectTable(ctx, collector.query, collector.dest)
+		if ctx.Err() != nil {
+			return docker, ctx.Err()
+		}
+		snapshot.skip(ctx, collector.name, reason, err)
+	}
+
+	byID := make(map[string]*Container, len(docker.Containers))
+	for i := range docker.Containers {
+		container := &docker.Containers[i]
+		container.Ports = []ContainerPort{}
+		container.Labels = map[string]string{}
+		byID[container.ID] = container
+	}
+	for _, port := range ports {
+		if container, ok := byID[port.ContainerID]; ok {
+			container.Ports = append(container.Ports, port)
+		}
+	}
+	for _, label := range labels {
+		if container, ok := byID[label.ContainerID]; ok {
+			container.Labels[label.Key] = label.Value
+		}
+	}
+
+	for i := range docker.Images {
+		docker.Images[i].Tags = SplitImageTags(docker.Images[i].RawTags)
+	}
+
+	sort.Slice(docker.Containers, func(i, j int) bool {
+		a, b := docker.Containers[i], docker.Containers[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	sort.Slice(docker.Images, func(i, j int) bool { return docker.Images[i].ID < docker.Images[j].ID })
+	return docker, nil
+}
+
+// SplitImageTags splits the comma separated tags column of docker_images.
+func SplitImageTags(tags string) []string {
+	split := []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			split = append(split, tag)
+		}
+	}
+	return split
+}
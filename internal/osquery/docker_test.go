@@ -0,0 +1,100 @@
+package osquery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setDockerTable registers a docker table's columns and rows on fake.
+func setDockerTable(fake *FakeQuerier, q tableQuery, rows []map[string]interface{}) {
+	info := make([]map[string]interface{}, len(q.Columns))
+	for i, column := range q.Columns {
+		columnType := ColumnText
+		switch column {
+		case "port", "host_port", "privileged":
+			columnType = ColumnInteger
+		case "created", "size_bytes":
+			columnType = ColumnBigInt
+		}
+		info[i] = map[string]interface{}{"name": column, "type": columnType}
+	}
+	fake.SetResult("PRAGMA table_info("+q.Table+");", info)
+	fake.SetResult("SELECT "+strings.Join(q.Columns, ", ")+" FROM "+q.Table+";", rows)
+}
+
+func TestGetDocker(t *testing.T) {
+	fake := NewFakeQuerier()
+	setDockerTable(fake, dockerContainersQuery, []map[string]interface{}{
+		{"id": "c3", "name": "/web", "image": "nginx:1.25", "privileged": "0"},
+		{"id": "c1", "name": "/web", "image": "nginx:1.25", "privileged": "1"},
+		{"id": "c2", "name": "/db", "image": "postgres:16", "privileged": "0"},
+	})
+	setDockerTable(fake, dockerContainerPortsQuery, []map[string]interface{}{
+		{"id": "c1", "type": "tcp", "port": "80", "host_ip": "0.0.0.0", "host_port": "8080"},
+		{"id": "c2", "type": "tcp", "port": "5432", "host_ip": "", "host_port": ""},
+		{"id": "gone", "type": "tcp", "port": "22", "host_ip": "", "host_port": ""},
+	})
+	setDockerTable(fake, dockerContainerLabelsQuery, []map[string]interface{}{
+		{"id": "c1", "key": "com.example.team", "value": "web"},
+		{"id": "c1", "key": "com.example.tier", "value": "frontend"},
+		{"id": "gone", "key": "orphan", "value": "x"},
+	})
+	setDockerTable(fake, dockerImagesQuery, []map[string]interface{}{
+		{"id": "sha256:bbb", "created": "1700000000", "size_bytes": "1000", "tags": "nginx:1.25, nginx:latest"},
+		{"id": "sha256:aaa", "created": "1700000000", "size_bytes": "2000", "tags": ""},
+	})
+
+	var snapshot Snapshot
+	docker, err := NewOsqueryClient(fake).GetDocker(context.Background(), &snapshot)
+	if err != nil {
+		t.Fatalf("GetDocker: %v", err)
+	}
+	if len(snapshot.Skipped) != 0 {
+		t.Fatalf("skipped collectors %+v", snapshot.Skipped)
+	}
+
+	var ids []string
+	for _, container := range docker.Containers {
+		ids = append(ids, container.ID)
+	}
+	if want := []string{"c2", "c1", "c3"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("containers in order %v, want %v", ids, want)
+	}
+
+	web := docker.Containers[1]
+	if want := []ContainerPort{{ContainerID: "c1", Type: "tcp", Port: 80, HostIP: "0.0.0.0", HostPort: 8080}}; !reflect.DeepEqual(web.Ports, want) {
+		t.Errorf("c1 ports = %+v, want %+v", web.Ports, want)
+	}
+	if want := map[string]string{"com.example.team": "web", "com.example.tier": "frontend"}; !reflect.DeepEqual(web.Labels, want) {
+		t.Errorf("c1 labels = %v, want %v", web.Labels, want)
+	}
+	if !web.Privileged {
+		t.Errorf("c1 is not privileged")
+	}
+	if other := docker.Containers[2]; len(other.Ports) != 0 || len(other.Labels) != 0 {
+		t.Errorf("c3 has ports %v and labels %v, want none", other.Ports, other.Labels)
+	}
+
+	if len(docker.Images) != 2 || docker.Images[0].ID != "sha256:aaa" || docker.Images[1].ID != "sha256:bbb" {
+		t.Fatalf("images = %+v, want sorted by id", docker.Images)
+	}
+	if want := []string{"nginx:1.25", "nginx:latest"}; !reflect.DeepEqual(docker.Images[1].Tags, want) {
+		t.Errorf("image tags = %v, want %v", docker.Images[1].Tags, want)
+	}
+}
+
+func TestSplitImageTags(t *testing.T) {
+	tests := map[string][]string{
+		"":                          {},
+		"nginx:latest":              {"nginx:latest"},
+		"nginx:1.25, nginx:latest":  {"nginx:1.25", "nginx:latest"},
+		" ,registry:5000/app:v1,, ": {"registry:5000/app:v1"},
+	}
+	for tags, want := range tests {
+		if got := SplitImageTags(tags); !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitImageTags(%q) = %q, want %q", tags, got, want)
+		}
+	}
+}
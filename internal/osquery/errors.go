@@ -0,0 +1,49 @@
+package osquery
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrQueryTimeout   = errors.New("osquery query timed out")
+	ErrOutputTooLarge = errors.New("osquery output exceeded size limit")
+	ErrSessionClosed  = errors.New("osquery session closed")
+)
+
+type ExitError struct {
+	Query    string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("osquery exited with code %d for query %q: %s", e.ExitCode, e.Query, e.Stderr)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+type ParseError struct {
+	Query string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse osquery output for query %q: %v", e.Query, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type QueryError struct {
+	Query   string
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("osquery rejected query %q: %s", e.Query, e.Message)
+}
@@ -0,0 +1,264 @@
+package osquery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultExtensionSocket = "/var/osquery/osquery.em"
+
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type extensionStatus struct {
+	Code    int32
+	Message string
+}
+
+// ExtensionQuerier talks to a running osqueryd through its extension manager
+// socket, so queries see the daemon's flags, config and event tables.
+type ExtensionQuerier struct {
+	socketPath string
+	timeout    time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *thriftReader
+	writer *thriftWriter
+	seq    int32
+}
+
+func NewExtensionQuerier(socketPath string, timeout time.Duration) *ExtensionQuerier {
+	if socketPath == "" {
+		socketPath = defaultExtensionSocket
+	}
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	return &ExtensionQuerier{
+		socketPath: socketPath,
+		timeout:    timeout,
+	}
+}
+
+func (e *ExtensionQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	rows, err := e.call(ctx, "query", query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		converted := make(map[string]interface{}, len(row))
+		for key, value := range row {
+			converted[key] = value
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+func (e *ExtensionQuerier) QueryColumns(ctx context.Context, query string) ([]Column, error) {
+	rows, err := e.call(ctx, "getQueryColumns", query)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]Column, 0, len(rows))
+	for _, row := range rows {
+		for name, columnType := range row {
+			columns = append(columns, Column{Name: name, Type: columnType})
+		}
+	}
+	return columns, nil
+}
+
+// Ping checks that the extension manager is reachable and healthy.
+func (e *ExtensionQuerier) Ping(ctx context.Context) error {
+	_, err := e.call(ctx, "ping")
+	return err
+}
+
+func (e *ExtensionQuerier) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.disconnectLocked()
+	return nil
+}
+
+// call invokes method with its string arguments. Every manager call used here
+// takes at most the SQL query, which is also what errors report.
+func (e *ExtensionQuerier) call(ctx context.Context, method string, args ...string) ([]map[string]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if e.conn == nil {
+		if err := e.connectLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	e.conn.SetDeadline(deadline)
+
+	conn := e.conn
+	stopWatch := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stopWatch()
+
+	query := strings.Join(args, " ")
+	e.seq++
+	status, rows, err := e.roundTripLocked(method, args, e.seq)
+	if err != nil {
+		// The stream position is unknown after a failed exchange.
+		e.disconnectLocked()
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return nil, fmt.Errorf("%w after %s: %s", ErrQueryTimeout, e.timeout, query)
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("osquery extension %s call failed: %w", method, err)
+	}
+
+	if status.Code != 0 {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("osquery extension %s failed: %s", method, status.Message)
+		}
+		return nil, &QueryError{Query: query, Message: status.Message}
+	}
+	return rows, nil
+}
+
+func (e *ExtensionQuerier) connectLocked(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "unix", e.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to osquery extension socket %s: %w", e.socketPath, err)
+	}
+
+	e.conn = conn
+	e.reader = &thriftReader{r: bufio.NewReader(conn)}
+	e.writer = &thriftWriter{w: bufio.NewWriter(conn)}
+	return nil
+}
+
+func (e *ExtensionQuerier) disconnectLocked() {
+	if e.conn == nil {
+		return
+	}
+
+	e.conn.Close()
+	e.conn = nil
+	e.reader = nil
+	e.writer = nil
+}
+
+// roundTripLocked sends `ExtensionResponse method(1: string sql)` and decodes
+// the ExtensionResponse {1: ExtensionStatus status, 2: list<map> response}.
+// ping takes no arguments and returns the ExtensionStatus {1: i32 code,
+// 2: string message} itself, which the same walk picks up by field type.
+func (e *ExtensionQuerier) roundTripLocked(method string, args []string, seqID int32) (extensionStatus, []map[string]string, error) {
+	var status extensionStatus
+
+	w := e.writer
+	w.writeMessageBegin(method, thriftMessageCall, seqID)
+	for i, arg := range args {
+		w.writeFieldBegin(thriftString, int16(i+1))
+		w.writeString(arg)
+	}
+	w.writeFieldStop()
+	if err := w.flush(); err != nil {
+		return status, nil, err
+	}
+
+	r := e.reader
+	name, messageType, replySeq, err := r.readMessageBegin()
+	if err != nil {
+		return status, nil, err
+	}
+	if messageType == thriftMessageError {
+		message, err := readApplicationException(r)
+		if err != nil {
+			return status, nil, err
+		}
+		return status, nil, fmt.Errorf("osquery extension manager raised: %s", message)
+	}
+	if messageType != thriftMessageReply || name != method || replySeq != seqID {
+		return status, nil, fmt.Errorf("%w: unexpected reply %q (type %d, seq %d)", errThriftMalformed, name, messageType, replySeq)
+	}
+
+	readStatus := func(fieldType byte, id int16) (bool, error) {
+		switch {
+		case id == 1 && fieldType == thriftI32:
+			code, err := r.readI32()
+			status.Code = code
+			return true, err
+		case id == 2 && fieldType == thriftString:
+			message, err := r.readString()
+			status.Message = message
+			return true, err
+		}
+		return false, nil
+	}
+
+	var rows []map[string]string
+	gotSuccess := false
+	err = r.readStruct(func(fieldType byte, id int16) (bool, error) {
+		if id != 0 || fieldType != thriftStruct {
+			return false, nil
+		}
+		gotSuccess = true
+		return true, r.readStruct(func(fieldType byte, id int16) (bool, error) {
+			switch {
+			case id == 1 && fieldType == thriftStruct:
+				return true, r.readStruct(readStatus)
+			case id == 1 && fieldType == thriftI32, id == 2 && fieldType == thriftString:
+				return readStatus(fieldType, id)
+			case id == 2 && fieldType == thriftList:
+				list, err := r.readStringMapList()
+				rows = list
+				return true, err
+			}
+			return false, nil
+		})
+	})
+	if err != nil {
+		return status, nil, err
+	}
+	if !gotSuccess {
+		return status, nil, fmt.Errorf("%w: %s reply has no result", errThriftMalformed, method)
+	}
+
+	return status, rows, nil
+}
+
+func readApplicationException(r *thriftReader) (string, error) {
+	var message string
+	err := r.readStruct(func(fieldType byte, id int16) (bool, error) {
+		if id == 1 && fieldType == thriftString {
+			value, err := r.readString()
+			message = value
+			return true, err
+		}
+		return false, nil
+	})
+	return message, err
+}
@@ -0,0 +1,253 @@
+package osquery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type extensionCall struct {
+	Method string
+	Args   []string
+	SeqID  int32
+}
+
+// serveExtension runs a fake extension manager on a unix socket. reply writes
+// the whole response to each call; returning false closes the connection.
+func serveExtension(t *testing.T, reply func(w *thriftWriter, call extensionCall) bool) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "osquery.em")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveExtensionConn(conn, reply)
+		}
+	}()
+	return socketPath
+}
+
+func serveExtensionConn(conn net.Conn, reply func(w *thriftWriter, call extensionCall) bool) {
+	defer conn.Close()
+
+	r := &thriftReader{r: bufio.NewReader(conn)}
+	w := &thriftWriter{w: bufio.NewWriter(conn)}
+	for {
+		method, _, seqID, err := r.readMessageBegin()
+		if err != nil {
+			return
+		}
+		call := extensionCall{Method: method, SeqID: seqID}
+		err = r.readStruct(func(fieldType byte, _ int16) (bool, error) {
+			if fieldType != thriftString {
+				return false, nil
+			}
+			arg, err := r.readString()
+			call.Args = append(call.Args, arg)
+			return true, err
+		})
+		if err != nil {
+			return
+		}
+
+		keepOpen := reply(w, call)
+		if w.flush() != nil || !keepOpen {
+			return
+		}
+	}
+}
+
+func writeStatus(w *thriftWriter, id int16, code int32, message string) {
+	w.writeFieldBegin(thriftStruct, id)
+	w.writeFieldBegin(thriftI32, 1)
+	w.writeI32(code)
+	w.writeFieldBegin(thriftString, 2)
+	w.writeString(message)
+	w.writeFieldStop()
+}
+
+func writeResponse(w *thriftWriter, call extensionCall, code int32, message string, rows []map[string]string) {
+	w.writeMessageBegin(call.Method, thriftMessageReply, call.SeqID)
+	w.writeFieldBegin(thriftStruct, 0)
+	writeStatus(w, 1, code, message)
+	w.writeFieldBegin(thriftList, 2)
+	w.writeByte(thriftMap)
+	w.writeI32(int32(len(rows)))
+	for _, row := range rows {
+		w.writeByte(thriftString)
+		w.writeByte(thriftString)
+		w.writeI32(int32(len(row)))
+		for key, value := range row {
+			w.writeString(key)
+			w.writeString(value)
+		}
+	}
+	w.writeFieldStop()
+	w.writeFieldStop()
+}
+
+func newTestExtensionQuerier(t *testing.T, socketPath string) *ExtensionQuerier {
+	t.Helper()
+
+	e := NewExtensionQuerier(socketPath, 5*time.Second)
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestExtensionQuerierPing(t *testing.T) {
+	var code atomic.Int32
+	socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+		if call.Method != "ping" || len(call.Args) != 0 {
+			t.Errorf("unexpected call %+v", call)
+		}
+		w.writeMessageBegin(call.Method, thriftMessageReply, call.SeqID)
+		writeStatus(w, 0, code.Load(), "OK")
+		w.writeFieldStop()
+		return true
+	})
+	e := newTestExtensionQuerier(t, socketPath)
+
+	if err := e.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	code.Store(1)
+	if err := e.Ping(context.Background()); err == nil {
+		t.Fatal("Ping succeeded with a failing status")
+	}
+}
+
+func TestExtensionQuerierQuery(t *testing.T) {
+	socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+		if call.Method != "query" || !reflect.DeepEqual(call.Args, []string{"SELECT version FROM osquery_info;"}) {
+			t.Errorf("unexpected call %+v", call)
+		}
+		writeResponse(w, call, 0, "OK", []map[string]string{{"version": "5.11.0"}})
+		return true
+	})
+	e := newTestExtensionQuerier(t, socketPath)
+
+	for i := 0; i < 2; i++ {
+		rows, err := e.Query(context.Background(), "SELECT version FROM osquery_info;")
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		want := []map[string]interface{}{{"version": "5.11.0"}}
+		if !reflect.DeepEqual(rows, want) {
+			t.Fatalf("rows = %v, want %v", rows, want)
+		}
+	}
+}
+
+func TestExtensionQuerierQueryError(t *testing.T) {
+	socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+		writeResponse(w, call, 1, "no such table: nope", nil)
+		return true
+	})
+	e := newTestExtensionQuerier(t, socketPath)
+
+	_, err := e.Query(context.Background(), "SELECT * FROM nope;")
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Message != "no such table: nope" {
+		t.Fatalf("err = %v, want QueryError", err)
+	}
+}
+
+func TestExtensionQuerierQueryColumns(t *testing.T) {
+	socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+		if call.Method != "getQueryColumns" {
+			t.Errorf("unexpected method %q", call.Method)
+		}
+		writeResponse(w, call, 0, "OK", []map[string]string{{"uid": "BIGINT"}, {"username": "TEXT"}})
+		return true
+	})
+	e := newTestExtensionQuerier(t, socketPath)
+
+	columns, err := e.QueryColumns(context.Background(), "SELECT * FROM users;")
+	if err != nil {
+		t.Fatalf("QueryColumns: %v", err)
+	}
+	want := []Column{{Name: "uid", Type: "BIGINT"}, {Name: "username", Type: "TEXT"}}
+	if !reflect.DeepEqual(columns, want) {
+		t.Fatalf("columns = %v, want %v", columns, want)
+	}
+}
+
+func TestExtensionQuerierTruncatedReply(t *testing.T) {
+	var calls atomic.Int32
+	socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+		if calls.Add(1) == 1 {
+			w.writeMessageBegin(call.Method, thriftMessageReply, call.SeqID)
+			w.writeFieldBegin(thriftStruct, 0)
+			w.writeFieldBegin(thriftList, 2)
+			return false
+		}
+		writeResponse(w, call, 0, "OK", nil)
+		return true
+	})
+	e := newTestExtensionQuerier(t, socketPath)
+
+	if _, err := e.Query(context.Background(), "SELECT 1;"); err == nil {
+		t.Fatal("Query succeeded on a truncated reply")
+	}
+	if _, err := e.Query(context.Background(), "SELECT 1;"); err != nil {
+		t.Fatalf("Query after reconnect: %v", err)
+	}
+}
+
+func TestExtensionQuerierOversizedReply(t *testing.T) {
+	tests := map[string]func(w *thriftWriter){
+		"list": func(w *thriftWriter) {
+			w.writeByte(thriftMap)
+			w.writeI32(1<<31 - 1)
+		},
+		"map": func(w *thriftWriter) {
+			w.writeByte(thriftMap)
+			w.writeI32(1)
+			w.writeByte(thriftString)
+			w.writeByte(thriftString)
+			w.writeI32(1<<31 - 1)
+		},
+		"string": func(w *thriftWriter) {
+			w.writeByte(thriftMap)
+			w.writeI32(1)
+			w.writeByte(thriftString)
+			w.writeByte(thriftString)
+			w.writeI32(1)
+			w.writeI32(1<<31 - 1)
+		},
+	}
+
+	for name, writeRows := range tests {
+		t.Run(name, func(t *testing.T) {
+			socketPath := serveExtension(t, func(w *thriftWriter, call extensionCall) bool {
+				w.writeMessageBegin(call.Method, thriftMessageReply, call.SeqID)
+				w.writeFieldBegin(thriftStruct, 0)
+				w.writeFieldBegin(thriftList, 2)
+				writeRows(w)
+				return false
+			})
+			e := newTestExtensionQuerier(t, socketPath)
+
+			_, err := e.Query(context.Background(), "SELECT 1;")
+			if !errors.Is(err, errThriftMalformed) {
+				t.Fatalf("err = %v, want %v", err, errThriftMalformed)
+			}
+		})
+	}
+}
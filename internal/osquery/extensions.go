@@ -0,0 +1,192 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Extension struct {
+	Source      string `json:"source" osquery:"-"`
+	UID         int64  `json:"uid" osquery:"uid"`
+	Username    string `json:"username" osquery:"username"`
+	Browser     string `json:"browser,omitempty" osquery:"browser"`
+	Name        string `json:"name" osquery:"name"`
+	Identifier  string `json:"identifier" osquery:"identifier"`
+	Version     string `json:"version" osquery:"version"`
+	Permissions string `json:"permissions,omitempty" osquery:"permissions"`
+	Enabled     bool   `json:"enabled" osquery:"enabled"`
+	Path        string `json:"path,omitempty" osquery:"path"`
+}
+
+// extensionSource maps a per-user extension table onto Extension. Columns
+// maps each Extension column to an expression over the table aliased "e";
+// an expression whose column the table lacks falls back to the default.
+type extensionSource struct {
+	Name       string
+	Table      string
+	MinVersion Version
+	Columns    []extensionColumn
+	Required   []string
+}
+
+type extensionColumn struct {
+	Alias   string
+	Column  string
+	Expr    string
+	Default string
+}
+
+var extensionSources = []extensionSource{
+	{
+		Name:  "chrome",
+		Table: "chrome_extensions",
+		Columns: []extensionColumn{
+			{Alias: "browser", Column: "browser_type", Default: "'chrome'"},
+			{Alias: "name", Column: "name"},
+			{Alias: "identifier", Column: "identifier"},
+			{Alias: "version", Column: "version"},
+			{Alias: "permissions", Column: "permissions"},
+			{Alias: "enabled", Column: "state", Default: "1"},
+			{Alias: "path", Column: "path"},
+		},
+		Required: []string{"uid", "identifier"},
+	},
+	{
+		Name:  "firefox",
+		Table: "firefox_addons",
+		Columns: []extensionColumn{
+			{Alias: "browser", Default: "'firefox'"},
+			{Alias: "name", Column: "name"},
+			{Alias: "identifier", Column: "identifier"},
+			{Alias: "version", Column: "version"},
+			{Alias: "permissions"},
+			{Alias: "enabled", Column: "active", Default: "1"},
+			{Alias: "path", Column: "path"},
+		},
+		Required: []string{"uid", "identifier"},
+	},
+	{
+		Name:  "safari",
+		Table: "safari_extensions",
+		Columns: []extensionColumn{
+			{Alias: "browser", Default: "'safari'"},
+			{Alias: "name", Column: "name"},
+			{Alias: "identifier", Column: "identifier"},
+			{Alias: "version", Column: "version"},
+			{Alias: "permissions"},
+			{Alias: "enabled", Default: "1"},
+			{Alias: "path", Column: "path"},
+		},
+		Required: []string{"uid", "identifier"},
+	},
+	{
+		Name:       "vscode",
+		Table:      "vscode_extensions",
+		MinVersion: Version{Major: 5, Minor: 3},
+		Columns: []extensionColumn{
+			{Alias: "browser"},
+			{Alias: "name", Column: "name"},
+			{Alias: "identifier", Column: "publisher", Expr: "COALESCE(e.publisher, '') || '.' || COALESCE(e.name, '')", Default: "e.name"},
+			{Alias: "version", Column: "version"},
+			{Alias: "permissions"},
+			{Alias: "enabled", Default: "1"},
+			{Alias: "path", Column: "path"},
+		},
+		Required: []string{"uid", "name"},
+	},
+}
+
+// SetCollectExtensions turns the browser and IDE extension collector on.
+// It is off by default because the tables walk every user's profile.
+func (c *OsqueryClient) SetCollectExtensions(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collectExtensions = enabled
+}
+
+// GetExtensions collects extensions from every source table the host has.
+// Sources that do not report whether an extension is enabled count it as
+// enabled.
+func (c *OsqueryClient) GetExtensions(ctx context.Context, snapshot *Snapshot) ([]Extension, error) {
+	c.mu.Lock()
+	enabled := c.collectExtensions
+	c.mu.Unlock()
+
+	if !enabled {
+		return nil, nil
+	}
+
+	var extensions []Extension
+	for _, source := range extensionSources {
+		found, reason, err := c.queryExtensionSource(ctx, source)
+		if ctx.Err() != nil {
+			return extensions, ctx.Err()
+		}
+		snapshot.skip(ctx, "extensions/"+source.Name, reason, err)
+
+		for i := range found {
+			found[i].Source = source.Name
+		}
+		extensions = append(extensions, found...)
+	}
+
+	sort.SliceStable(extensions, func(i, j int) bool {
+		if extensions[i].Username != extensions[j].Username {
+			return extensions[i].Username < extensions[j].Username
+		}
+		return extensions[i].Name < extensions[j].Name
+	})
+	return extensions, nil
+}
+
+// Extension tables need a uid constraint, so they are joined against users
+// in the same way as authorized_keys.
+func (c *OsqueryClient) queryExtensionSource(ctx context.Context, source extensionSource) ([]Extension, string, error) {
+	if reason := c.requireVersion(source.Table, source.MinVersion); reason != "" {
+		return nil, reason, nil
+	}
+	for _, table := range []string{"users", source.Table} {
+		if !c.HasTable(table) {
+			return nil, fmt.Sprintf("table %s is not available", table), nil
+		}
+	}
+	for _, column := range source.Required {
+		if !c.HasColumn(ctx, source.Table, column) {
+			return nil, fmt.Sprintf("table %s has no column %s", source.Table, column), nil
+		}
+	}
+
+	selects := []string{"u.uid", "u.username"}
+	for _, column := range source.Columns {
+		expr := column.Default
+		if column.Column != "" && c.HasColumn(ctx, source.Table, column.Column) {
+			expr = column.Expr
+			if expr == "" {
+				expr = "e." + column.Column
+			}
+		}
+		if expr == "" {
+			expr = "''"
+		}
+		selects = append(selects, expr+" AS "+column.Alias)
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") +
+		" FROM users u JOIN " + source.Table + " e ON e.uid = u.uid;"
+
+	result, err := c.Query(ctx, query)
+	if err != nil {
+		if isMissingSchemaError(err) {
+			return nil, err.Error(), nil
+		}
+		return nil, "", fmt.Errorf("failed to query %s: %w", source.Table, err)
+	}
+
+	var extensions []Extension
+	if err := result.Decode(&extensions); err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", source.Table, err)
+	}
+	return extensions, "", nil
+}
@@ -0,0 +1,97 @@
+package osquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func extensionSourceNamed(t *testing.T, name string) extensionSource {
+	t.Helper()
+
+	for _, source := range extensionSources {
+		if source.Name == name {
+			return source
+		}
+	}
+	t.Fatalf("no %s extension source", name)
+	return extensionSource{}
+}
+
+func TestQueryExtensionSourceColumnFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		columns []string
+		query   string
+		reason  string
+	}{
+		{
+			name:    "chrome with every column",
+			source:  "chrome",
+			columns: []string{"uid", "browser_type", "name", "identifier", "version", "permissions", "state", "path"},
+			query: "SELECT u.uid, u.username, e.browser_type AS browser, e.name AS name, e.identifier AS identifier, " +
+				"e.version AS version, e.permissions AS permissions, e.state AS enabled, e.path AS path " +
+				"FROM users u JOIN chrome_extensions e ON e.uid = u.uid;",
+		},
+		{
+			name:    "older chrome falls back to defaults",
+			source:  "chrome",
+			columns: []string{"uid", "name", "identifier", "version"},
+			query: "SELECT u.uid, u.username, 'chrome' AS browser, e.name AS name, e.identifier AS identifier, " +
+				"e.version AS version, '' AS permissions, 1 AS enabled, '' AS path " +
+				"FROM users u JOIN chrome_extensions e ON e.uid = u.uid;",
+		},
+		{
+			name:    "vscode joins publisher and name",
+			source:  "vscode",
+			columns: []string{"uid", "name", "publisher", "version", "path"},
+			query: "SELECT u.uid, u.username, '' AS browser, e.name AS name, " +
+				"COALESCE(e.publisher, '') || '.' || COALESCE(e.name, '') AS identifier, " +
+				"e.version AS version, '' AS permissions, 1 AS enabled, e.path AS path " +
+				"FROM users u JOIN vscode_extensions e ON e.uid = u.uid;",
+		},
+		{
+			name:    "vscode without publisher uses the name",
+			source:  "vscode",
+			columns: []string{"uid", "name", "version"},
+			query: "SELECT u.uid, u.username, '' AS browser, e.name AS name, e.name AS identifier, " +
+				"e.version AS version, '' AS permissions, 1 AS enabled, '' AS path " +
+				"FROM users u JOIN vscode_extensions e ON e.uid = u.uid;",
+		},
+		{
+			name:    "missing required column skips the source",
+			source:  "firefox",
+			columns: []string{"name", "identifier"},
+			reason:  "table firefox_addons has no column uid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := extensionSourceNamed(t, tt.source)
+			rows := []map[string]interface{}{{
+				"uid": "501", "username": "alice", "browser": "", "name": "Ext", "identifier": "pub.ext",
+				"version": "1.0", "permissions": "", "enabled": "1", "path": "",
+			}}
+
+			fake := NewFakeQuerier()
+			fake.SetResult("PRAGMA table_info("+source.Table+");", tableInfoRows(tt.columns...))
+			fake.SetResult(tt.query, rows)
+			c := NewOsqueryClient(fake)
+
+			extensions, reason, err := c.queryExtensionSource(context.Background(), source)
+			if err != nil || reason != tt.reason {
+				t.Fatalf("queryExtensionSource = (%q, %v), want (%q, nil)", reason, err, tt.reason)
+			}
+			if tt.reason != "" {
+				return
+			}
+
+			want := []Extension{{UID: 501, Username: "alice", Name: "Ext", Identifier: "pub.ext", Version: "1.0", Enabled: true}}
+			if !reflect.DeepEqual(extensions, want) {
+				t.Fatalf("extensions = %+v, want %+v", extensions, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type FakeQuerier struct {
+	mu      sync.RWMutex
+	results map[string][]map[string]interface{}
+	errors  map[string]error
+}
+
+func NewFakeQuerier() *FakeQuerier {
+	return &FakeQuerier{
+		results: make(map[string][]map[string]interface{}),
+		errors:  make(map[string]error),
+	}
+}
+
+func (f *FakeQuerier) SetResult(query string, rows []map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.results[query] = rows
+	delete(f.errors, query)
+}
+
+func (f *FakeQuerier) SetError(query string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors[query] = err
+	delete(f.results, query)
+}
+
+func (f *FakeQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err, ok := f.errors[query]; ok {
+		return nil, err
+	}
+
+	rows, ok := f.results[query]
+	if !ok {
+		return nil, fmt.Errorf("no fake result registered for query: %s", query)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,155 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	FileCreated  = "created"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+
+	// fimCollectorPrefix prefixes the skip record of every watched pattern.
+	fimCollectorPrefix = "fim/"
+)
+
+type FileEntry struct {
+	Path   string `json:"path" osquery:"path"`
+	SHA256 string `json:"sha256" osquery:"sha256"`
+	Size   int64  `json:"size" osquery:"size"`
+	Mode   string `json:"mode" osquery:"mode"`
+	UID    int64  `json:"uid" osquery:"uid"`
+	GID    int64  `json:"gid" osquery:"gid"`
+	Mtime  int64  `json:"mtime" osquery:"mtime"`
+}
+
+// FileChange is one file created, modified or deleted between two snapshots.
+// Previous is nil for created files and Current is nil for deleted ones.
+type FileChange struct {
+	Path     string     `json:"path"`
+	Type     string     `json:"type"`
+	Previous *FileEntry `json:"previous,omitempty"`
+	Current  *FileEntry `json:"current,omitempty"`
+}
+
+// SetFileIntegrityPaths sets the paths watched for file integrity monitoring.
+// Patterns use shell-style globs: "*" matches within a directory and "**"
+// matches recursively, e.g. "/etc/**" or "/usr/local/bin/*".
+func (c *OsqueryClient) SetFileIntegrityPaths(patterns []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fimPaths = patterns
+}
+
+// fileLikePattern converts a glob to the LIKE pattern osquery expands for
+// the file table, where "%" matches within a directory and "%%" recurses.
+func fileLikePattern(glob string) string {
+	return strings.NewReplacer("**", "%%", "*", "%").Replace(glob)
+}
+
+func (c *OsqueryClient) GetFileIntegrity(ctx context.Context, snapshot *Snapshot) ([]FileEntry, error) {
+	c.mu.Lock()
+	patterns := c.fimPaths
+	c.mu.Unlock()
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	snapshot.FilePatterns = patterns
+
+	for _, table := range []string{"file", "hash"} {
+		if !c.HasTable(table) {
+			for _, pattern := range patterns {
+				snapshot.skip(ctx, fimCollectorPrefix+pattern, fmt.Sprintf("table %s is not available", table), nil)
+			}
+			return nil, nil
+		}
+	}
+
+	seen := make(map[string]bool)
+	var files []FileEntry
+	for _, pattern := range patterns {
+		query := "SELECT f.path, h.sha256, f.size, f.mode, f.uid, f.gid, f.mtime" +
+			" FROM file f LEFT JOIN hash h ON h.path = f.path" +
+			" WHERE f.path LIKE " + sqlString(fileLikePattern(pattern)) + " AND f.type = 'regular';"
+
+		result, err := c.Query(ctx, query)
+		if ctx.Err() != nil {
+			return files, ctx.Err()
+		}
+		if err != nil {
+			reason := ""
+			if isMissingSchemaError(err) {
+				reason, err = err.Error(), nil
+			}
+			snapshot.skip(ctx, fimCollectorPrefix+pattern, reason, err)
+			continue
+		}
+
+		var found []FileEntry
+		if err := result.Decode(&found); err != nil {
+			snapshot.skip(ctx, fimCollectorPrefix+pattern, "", fmt.Errorf("failed to decode files: %w", err))
+			continue
+		}
+
+		for _, file := range found {
+			if !seen[file.Path] {
+				seen[file.Path] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// FileIntegrityComplete reports whether every watched pattern was collected,
+// so the snapshot's files can be compared with another snapshot's.
+func FileIntegrityComplete(skipped []SkippedCollector) bool {
+	for _, collector := range skipped {
+		if strings.HasPrefix(collector.Name, fimCollectorPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffFiles compares the watched files of two snapshots. Only content,
+// size, permission and ownership changes count as modifications; a bare
+// mtime change does not.
+func DiffFiles(previous, current []FileEntry) []FileChange {
+	before := make(map[string]FileEntry, len(previous))
+	for _, file := range previous {
+		before[file.Path] = file
+	}
+
+	changes := []FileChange{}
+	for i := range current {
+		file := &current[i]
+		old, ok := before[file.Path]
+		delete(before, file.Path)
+
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: file.Path, Type: FileCreated, Current: file})
+		case old.SHA256 != file.SHA256 || old.Size != file.Size || old.Mode != file.Mode ||
+			old.UID != file.UID || old.GID != file.GID:
+			old := old
+			changes = append(changes, FileChange{Path: file.Path, Type: FileModified, Previous: &old, Current: file})
+		}
+	}
+
+	for _, file := range previous {
+		if _, ok := before[file.Path]; ok {
+			file := file
+			changes = append(changes, FileChange{Path: file.Path, Type: FileDeleted, Previous: &file})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
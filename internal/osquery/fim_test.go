@@ -0,0 +1,52 @@
+package osquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	previous := []FileEntry{
+		{Path: "/etc/hosts", SHA256: "aa", Size: 10, Mode: "0644", Mtime: 1},
+		{Path: "/etc/passwd", SHA256: "bb", Size: 20, Mode: "0644", Mtime: 1},
+		{Path: "/etc/shadow", SHA256: "cc", Size: 30, Mode: "0640", Mtime: 1},
+		{Path: "/etc/sudoers", SHA256: "dd", Size: 40, Mode: "0440", Mtime: 1},
+	}
+	current := []FileEntry{
+		{Path: "/etc/sudoers", SHA256: "dd", Size: 40, Mode: "0644", Mtime: 1},
+		{Path: "/etc/hosts", SHA256: "aa", Size: 10, Mode: "0644", Mtime: 2},
+		{Path: "/etc/passwd", SHA256: "b2", Size: 21, Mode: "0644", Mtime: 2},
+		{Path: "/etc/cron.d/job", SHA256: "ee", Size: 5, Mode: "0644", Mtime: 2},
+	}
+
+	changes := DiffFiles(previous, current)
+
+	want := []FileChange{
+		{Path: "/etc/cron.d/job", Type: FileCreated, Current: &current[3]},
+		{Path: "/etc/passwd", Type: FileModified, Previous: &previous[1], Current: &current[2]},
+		{Path: "/etc/shadow", Type: FileDeleted, Previous: &previous[2]},
+		{Path: "/etc/sudoers", Type: FileModified, Previous: &previous[3], Current: &current[0]},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("DiffFiles = %+v, want %+v", changes, want)
+	}
+}
+
+func TestDiffFilesEmpty(t *testing.T) {
+	if changes := DiffFiles(nil, nil); changes == nil || len(changes) != 0 {
+		t.Fatalf("DiffFiles(nil, nil) = %#v, want an empty slice", changes)
+	}
+}
+
+func TestFileLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"/etc/**":          "/etc/%%",
+		"/usr/local/bin/*": "/usr/local/bin/%",
+		"/etc/hosts":       "/etc/hosts",
+	}
+	for glob, want := range tests {
+		if got := fileLikePattern(glob); got != want {
+			t.Errorf("fileLikePattern(%q) = %q, want %q", glob, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package osquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Fixture files map the exact query text to the rows osqueryi --json printed
+// for it, so a recorded run can be replayed on hosts without osquery.
+type FixtureQuerier struct {
+	path     string
+	fixtures map[string][]map[string]interface{}
+}
+
+func NewFixtureQuerier(path string) (*FixtureQuerier, error) {
+	if path == "" {
+		return nil, fmt.Errorf("fixture backend requires a fixture file path")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	fixtures := make(map[string][]map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture file: %w", err)
+	}
+
+	return &FixtureQuerier{path: path, fixtures: fixtures}, nil
+}
+
+func (f *FixtureQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, ok := f.fixtures[query]
+	if !ok {
+		return nil, fmt.Errorf("no recorded fixture for query in %s: %s", f.path, query)
+	}
+	return rows, nil
+}
+
+type RecordingQuerier struct {
+	next     Querier
+	mu       sync.Mutex
+	recorded map[string][]map[string]interface{}
+}
+
+func NewRecordingQuerier(next Querier) *RecordingQuerier {
+	return &RecordingQuerier{
+		next:     next,
+		recorded: make(map[string][]map[string]interface{}),
+	}
+}
+
+func (r *RecordingQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	rows, err := r.next.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.recorded[query] = rows
+	r.mu.Unlock()
+
+	return rows, nil
+}
+
+func (r *RecordingQuerier) Save(path string) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.recorded, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package osquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type KernelModule struct {
+	Name    string `json:"name" osquery:"name"`
+	Size    int64  `json:"size" osquery:"size"`
+	UsedBy  string `json:"used_by" osquery:"used_by"`
+	Status  string `json:"status" osquery:"status"`
+	Address string `json:"address,omitempty" osquery:"address"`
+}
+
+type SystemControl struct {
+	Name         string `json:"name" osquery:"name"`
+	CurrentValue string `json:"current_value" osquery:"current_value"`
+	ConfigValue  string `json:"config_value,omitempty" osquery:"config_value"`
+	Subsystem    string `json:"subsystem,omitempty" osquery:"subsystem"`
+	Type         string `json:"type,omitempty" osquery:"type"`
+}
+
+// SysctlBaseline maps sysctl names to the values a hardened host should have.
+type SysctlBaseline map[string]string
+
+type SysctlDeviation struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Missing  bool   `json:"missing"`
+}
+
+// SecuritySysctls are collected on every host whether or not they appear in
+// the baseline, so a baseline can be written against stored history.
+var SecuritySysctls = []string{
+	"fs.protected_hardlinks",
+	"fs.protected_symlinks",
+	"fs.suid_dumpable",
+	"kernel.dmesg_restrict",
+	"kernel.kexec_load_disabled",
+	"kernel.kptr_restrict",
+	"kernel.randomize_va_space",
+	"kernel.sysrq",
+	"kernel.unprivileged_bpf_disabled",
+	"kernel.yama.ptrace_scope",
+	"net.ipv4.conf.all.accept_redirects",
+	"net.ipv4.conf.all.accept_source_route",
+	"net.ipv4.conf.all.log_martians",
+	"net.ipv4.conf.all.rp_filter",
+	"net.ipv4.conf.all.send_redirects",
+	"net.ipv4.icmp_echo_ignore_broadcasts",
+	"net.ipv4.ip_forward",
+	"net.ipv4.tcp_syncookies",
+	"net.ipv6.conf.all.accept_ra",
+	"net.ipv6.conf.all.accept_redirects",
+}
+
+var kernelModulesQuery = tableQuery{
+	Table:    "kernel_modules",
+	Columns:  []string{"name", "size", "used_by", "status", "address"},
+	Required: []string{"name"},
+}
+
+// LoadSysctlBaseline reads a JSON object of sysctl names to expected values.
+// Values may be written as strings or numbers.
+func LoadSysctlBaseline(path string) (SysctlBaseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sysctl baseline: %w", err)
+	}
+
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse sysctl baseline %s: %w", path, err)
+	}
+
+	baseline := make(SysctlBaseline, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			baseline[name] = v
+		case json.Number:
+			baseline[name] = v.String()
+		default:
+			return nil, fmt.Errorf("sysctl baseline %s: value of %s must be a string or number", path, name)
+		}
+	}
+	return baseline, nil
+}
+
+// SetSysctlBaseline adds the baseline's sysctls to those collected.
+func (c *OsqueryClient) SetSysctlBaseline(baseline SysctlBaseline) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sysctlBaseline = baseline
+}
+
+func (c *OsqueryClient) sysctlNames() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range SecuritySysctls {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range c.sysctlBaseline {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *OsqueryClient) GetKernelModules(ctx context.Context) ([]KernelModule, string, error) {
+	var modules []KernelModule
+	reason, err := c.collectTable(ctx, kernelModulesQuery, &modules)
+	return modules, reason, err
+}
+
+func (c *OsqueryClient) GetSystemControls(ctx context.Context) ([]SystemControl, string, error) {
+	names := c.sysctlNames()
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = sqlString(name)
+	}
+
+	q := tableQuery{
+		Table:       "system_controls",
+		Columns:     []string{"name", "current_value", "config_value", "subsystem", "type"},
+		Required:    []string{"name", "current_value"},
+		Where:       "name IN (" + strings.Join(quoted, ", ") + ")",
+		WhereColumn: "name",
+	}
+
+	var controls []SystemControl
+	reason, err := c.collectTable(ctx, q, &controls)
+	return controls, reason, err
+}
+
+// CheckSysctlBaseline compares collected sysctls with the baseline. Values
+// are compared with runs of whitespace collapsed, since multi-value sysctls
+// such as net.ipv4.tcp_rmem are tab separated. A baselined sysctl that was
+// not collected is reported as missing. When the host's sysctls were not
+// collected at all there is nothing to compare and it returns nil.
+func CheckSysctlBaseline(controls []SystemControl, collected bool, baseline SysctlBaseline) []SysctlDeviation {
+	if !collected {
+		return nil
+	}
+
+	actual := make(map[string]string, len(controls))
+	for _, control := range controls {
+		actual[control.Name] = control.CurrentValue
+	}
+
+	deviations := []SysctlDeviation{}
+	for name, expected := range baseline {
+		value, ok := actual[name]
+		switch {
+		case !ok:
+			deviations = append(deviations, SysctlDeviation{Name: name, Expected: expected, Missing: true})
+		case normalizeSysctl(value) != normalizeSysctl(expected):
+			deviations = append(deviations, SysctlDeviation{Name: name, Expected: expected, Actual: value})
+		}
+	}
+
+	sort.Slice(deviations, func(i, j int) bool { return deviations[i].Name < deviations[j].Name })
+	return deviations
+}
+
+func normalizeSysctl(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
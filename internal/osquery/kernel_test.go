@@ -0,0 +1,37 @@
+package osquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckSysctlBaseline(t *testing.T) {
+	controls := []SystemControl{
+		{Name: "kernel.randomize_va_space", CurrentValue: "2"},
+		{Name: "net.ipv4.ip_forward", CurrentValue: "1"},
+		{Name: "net.ipv4.tcp_rmem", CurrentValue: "4096\t131072  6291456"},
+	}
+	baseline := SysctlBaseline{
+		"kernel.randomize_va_space": "2",
+		"net.ipv4.ip_forward":       "0",
+		"net.ipv4.tcp_rmem":         "4096 131072 6291456",
+		"kernel.kptr_restrict":      "2",
+	}
+
+	want := []SysctlDeviation{
+		{Name: "kernel.kptr_restrict", Expected: "2", Missing: true},
+		{Name: "net.ipv4.ip_forward", Expected: "0", Actual: "1"},
+	}
+	if got := CheckSysctlBaseline(controls, true, baseline); !reflect.DeepEqual(got, want) {
+		t.Errorf("deviations = %+v, want %+v", got, want)
+	}
+
+	if got := CheckSysctlBaseline(controls[:1], true, SysctlBaseline{"kernel.randomize_va_space": "2"}); len(got) != 0 {
+		t.Errorf("matching host has deviations %+v", got)
+	}
+
+	// A host whose sysctls were not collected is not missing every one of them.
+	if got := CheckSysctlBaseline(nil, false, baseline); got != nil {
+		t.Errorf("not collected host has deviations %+v", got)
+	}
+}
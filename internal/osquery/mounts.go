@@ -0,0 +1,43 @@
+package osquery
+
+type Mount struct {
+	Device          string `json:"device" osquery:"device"`
+	Path            string `json:"path" osquery:"path"`
+	Type            string `json:"type" osquery:"type"`
+	BlockSize       int64  `json:"blocks_size" osquery:"blocks_size"`
+	Blocks          int64  `json:"blocks" osquery:"blocks"`
+	BlocksFree      int64  `json:"blocks_free" osquery:"blocks_free"`
+	BlocksAvailable int64  `json:"blocks_available" osquery:"blocks_available"`
+	Inodes          int64  `json:"inodes" osquery:"inodes"`
+	InodesFree      int64  `json:"inodes_free" osquery:"inodes_free"`
+}
+
+// Pseudo filesystems report no blocks, so only mounts backed by storage are
+// collected.
+var mountsQuery = tableQuery{
+	Table: "mounts",
+	Columns: []string{
+		"device", "path", "type", "blocks_size", "blocks", "blocks_free", "blocks_available",
+		"inodes", "inodes_free",
+	},
+	Required:    []string{"path", "blocks", "blocks_free", "blocks_available"},
+	Where:       "blocks > 0",
+	WhereColumn: "blocks",
+}
+
+// UsedPercent is the share of space in use, computed like df: blocks
+// reserved for root count as neither used nor available.
+func (m Mount) UsedPercent() float64 {
+	used := m.Blocks - m.BlocksFree
+	if used+m.BlocksAvailable <= 0 {
+		return 0
+	}
+	return float64(used) * 100 / float64(used+m.BlocksAvailable)
+}
+
+func (m Mount) InodesUsedPercent() float64 {
+	if m.Inodes <= 0 {
+		return 0
+	}
+	return float64(m.Inodes-m.InodesFree) * 100 / float64(m.Inodes)
+}
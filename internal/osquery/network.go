@@ -0,0 +1,96 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type ListeningPort struct {
+	PID          int64  `json:"pid" osquery:"pid"`
+	Port         int    `json:"port" osquery:"port"`
+	Protocol     string `json:"protocol" osquery:"-"`
+	ProtocolNum  int    `json:"-" osquery:"protocol"`
+	Family       int    `json:"family" osquery:"family"`
+	Address      string `json:"address" osquery:"address"`
+	Interface    string `json:"interface,omitempty" osquery:"interface"`
+	InterfaceMAC string `json:"interface_mac,omitempty" osquery:"mac"`
+	ProcessName  string `json:"process_name" osquery:"process_name"`
+	ProcessPath  string `json:"process_path" osquery:"process_path"`
+	UID          int64  `json:"uid" osquery:"uid"`
+	Username     string `json:"username,omitempty" osquery:"username"`
+}
+
+// listeningPortsQuery joins listening sockets with their owning process and,
+// where the host provides the tables, the user and network interface.
+func (c *OsqueryClient) listeningPortsQuery() (string, string) {
+	for _, table := range []string{"listening_ports", "processes"} {
+		if !c.HasTable(table) {
+			return "", fmt.Sprintf("table %s is not available", table)
+		}
+	}
+
+	columns := []string{
+		"lp.pid", "lp.port", "lp.protocol", "lp.family", "lp.address",
+		"p.name AS process_name", "p.path AS process_path", "p.uid",
+	}
+	joins := []string{"LEFT JOIN processes p ON p.pid = lp.pid"}
+
+	if c.HasTable("users") {
+		columns = append(columns, "u.username")
+		joins = append(joins, "LEFT JOIN users u ON u.uid = p.uid")
+	}
+
+	if c.HasTable("interface_addresses") {
+		columns = append(columns, "ia.interface")
+		joins = append(joins, "LEFT JOIN interface_addresses ia ON ia.address = lp.address")
+
+		if c.HasTable("interface_details") {
+			columns = append(columns, "id.mac")
+			joins = append(joins, "LEFT JOIN interface_details id ON id.interface = ia.interface")
+		}
+	}
+
+	return "SELECT " + strings.Join(columns, ", ") +
+		" FROM listening_ports lp " + strings.Join(joins, " ") +
+		" WHERE lp.port != 0;", ""
+}
+
+func (c *OsqueryClient) GetListeningPorts(ctx context.Context) ([]ListeningPort, string, error) {
+	query, reason := c.listeningPortsQuery()
+	if reason != "" {
+		return nil, reason, nil
+	}
+
+	result, err := c.Query(ctx, query)
+	if err != nil {
+		if isMissingSchemaError(err) {
+			return nil, err.Error(), nil
+		}
+		return nil, "", fmt.Errorf("failed to get listening ports: %w", err)
+	}
+
+	var ports []ListeningPort
+	if err := result.Decode(&ports); err != nil {
+		return nil, "", fmt.Errorf("failed to decode listening ports: %w", err)
+	}
+
+	for i := range ports {
+		ports[i].Protocol = protocolName(ports[i].ProtocolNum)
+	}
+
+	return ports, "", nil
+}
+
+func protocolName(protocol int) string {
+	switch protocol {
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 132:
+		return "sctp"
+	default:
+		return fmt.Sprintf("%d", protocol)
+	}
+}
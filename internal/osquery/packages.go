@@ -0,0 +1,218 @@
+package osquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+type packageSource struct {
+	Name      string
+	Query     tableQuery
+	Platforms []string
+}
+
+var packageSources = []packageSource{
+	{
+		Name: "deb",
+		Query: tableQuery{
+			Table:       "deb_packages",
+			Columns:     []string{"name", "version", "arch", "maintainer AS vendor"},
+			Required:    []string{"name"},
+			Where:       "status LIKE '%installed'",
+			WhereColumn: "status",
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "rpm",
+		Query: tableQuery{
+			Table:    "rpm_packages",
+			Columns:  []string{"name", "version", "arch", "vendor", "install_time"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "apk",
+		Query: tableQuery{
+			Table:    "apk_packages",
+			Columns:  []string{"name", "version", "arch"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "portage",
+		Query: tableQuery{
+			Table:    "portage_packages",
+			Columns:  []string{"package AS name", "version", "build_time AS install_time"},
+			Required: []string{"package"},
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "snap",
+		Query: tableQuery{
+			Table:    "snap_packages",
+			Columns:  []string{"name", "version", "publisher AS vendor"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "flatpak",
+		Query: tableQuery{
+			Table:    "flatpak_packages",
+			Columns:  []string{"name", "version", "arch", "origin AS vendor"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"linux"},
+	},
+	{
+		Name: "app",
+		Query: tableQuery{
+			Table:    "apps",
+			Columns:  []string{"name", "bundle_version AS version"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"darwin"},
+	},
+	{
+		Name: "homebrew",
+		Query: tableQuery{
+			Table:    "homebrew_packages",
+			Columns:  []string{"name", "version"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"darwin"},
+	},
+	{
+		Name: "program",
+		Query: tableQuery{
+			Table:    "programs",
+			Columns:  []string{"name", "version", "publisher AS vendor"},
+			Required: []string{"name"},
+		},
+		Platforms: []string{"windows"},
+	},
+	{
+		Name: "python",
+		Query: tableQuery{
+			Table:    "python_packages",
+			Columns:  []string{"name", "version", "author AS vendor"},
+			Required: []string{"name"},
+		},
+	},
+	{
+		Name: "npm",
+		Query: tableQuery{
+			Table:    "npm_packages",
+			Columns:  []string{"name", "version", "author AS vendor"},
+			Required: []string{"name"},
+		},
+	},
+}
+
+func (s packageSource) supports(platform string) bool {
+	if len(s.Platforms) == 0 {
+		return true
+	}
+	for _, p := range s.Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *OsqueryClient) GetInstalledApps(ctx context.Context) ([]InstalledApp, []SkippedCollector, error) {
+	var apps []InstalledApp
+	var skipped []SkippedCollector
+	seen := make(map[InstalledApp]bool)
+
+	for _, source := range packageSources {
+		if !source.supports(runtime.GOOS) {
+			continue
+		}
+
+		query, reason := c.buildQuery(ctx, source.Query)
+		if reason != "" {
+			skipped = append(skipped, SkippedCollector{Name: "packages/" + source.Name, Reason: reason})
+			continue
+		}
+
+		sourceApps, err := c.querySource(ctx, source.Name, query)
+		if err != nil {
+			if isMissingSchemaError(err) {
+				skipped = append(skipped, SkippedCollector{Name: "packages/" + source.Name, Reason: err.Error()})
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to get installed apps from %s: %w", source.Query.Table, err)
+		}
+
+		for _, app := range sourceApps {
+			key := app
+			key.InstallTime = 0
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			apps = append(apps, app)
+		}
+	}
+
+	if apps == nil {
+		apps = []InstalledApp{}
+	}
+	return apps, skipped, nil
+}
+
+func (c *OsqueryClient) querySource(ctx context.Context, sourceName, query string) ([]InstalledApp, error) {
+	result, err := c.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []InstalledApp
+	if err := result.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode installed apps data: %w", err)
+	}
+
+	apps := make([]InstalledApp, 0, len(decoded))
+	for _, app := range decoded {
+		if app.Name == "" {
+			continue
+		}
+
+		if app.Version == "" {
+			app.Version = "unknown"
+		}
+
+		app.Source = sourceName
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// isMissingSchemaError reports whether osquery rejected a query because the
+// table or one of its columns does not exist on this host.
+func isMissingSchemaError(err error) bool {
+	var message string
+
+	var queryErr *QueryError
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &queryErr):
+		message = queryErr.Message
+	case errors.As(err, &exitErr):
+		message = exitErr.Stderr
+	default:
+		return false
+	}
+
+	return strings.Contains(message, "no such table") || strings.Contains(message, "no such column")
+}
@@ -0,0 +1,152 @@
+package osquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultPackInterval = 3600
+
+type Pack struct {
+	Name      string               `json:"-"`
+	Platform  string               `json:"platform"`
+	Version   string               `json:"version"`
+	Discovery []string             `json:"discovery"`
+	Queries   map[string]PackQuery `json:"queries"`
+}
+
+type PackQuery struct {
+	Name        string       `json:"-"`
+	Query       string       `json:"query"`
+	Interval    PackInterval `json:"interval"`
+	Platform    string       `json:"platform"`
+	Version     string       `json:"version"`
+	Snapshot    bool         `json:"snapshot"`
+	Removed     *bool        `json:"removed"`
+	Description string       `json:"description"`
+}
+
+// PackInterval accepts intervals written either as numbers or as strings,
+// both of which osquery allows in pack files.
+type PackInterval int
+
+func (i *PackInterval) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*i = 0
+		return nil
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return fmt.Errorf("invalid pack interval %s: %w", string(data), err)
+	}
+	*i = PackInterval(value)
+	return nil
+}
+
+// LoadPacks reads every *.json and *.conf pack in dir. The pack name is the
+// file name without its extension.
+func LoadPacks(dir string) ([]Pack, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read packs directory: %w", err)
+	}
+
+	var packs []Pack
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".conf") {
+			continue
+		}
+
+		pack, err := LoadPack(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	return packs, nil
+}
+
+func LoadPack(path string) (Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pack{}, fmt.Errorf("failed to read pack %s: %w", path, err)
+	}
+
+	var pack Pack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return Pack{}, fmt.Errorf("failed to parse pack %s: %w", path, err)
+	}
+
+	pack.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for name, query := range pack.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return Pack{}, fmt.Errorf("pack %s: query %s has no SQL", pack.Name, name)
+		}
+		query.Name = name
+		if query.Interval <= 0 {
+			query.Interval = defaultPackInterval
+		}
+		pack.Queries[name] = query
+	}
+
+	return pack, nil
+}
+
+// SortedQueries returns the pack's queries ordered by name.
+func (p Pack) SortedQueries() []PackQuery {
+	queries := make([]PackQuery, 0, len(p.Queries))
+	for _, query := range p.Queries {
+		queries = append(queries, query)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Name < queries[j].Name
+	})
+	return queries
+}
+
+// MatchesPlatform implements osquery's platform filter: a comma-separated list
+// of platforms, where "posix" covers every non-Windows OS and an empty value,
+// "all" or "any" matches everything.
+func MatchesPlatform(filter, goos string) bool {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return true
+	}
+
+	for _, platform := range strings.Split(filter, ",") {
+		switch strings.TrimSpace(strings.ToLower(platform)) {
+		case "all", "any":
+			return true
+		case "posix":
+			if goos != "windows" {
+				return true
+			}
+		case goos:
+			return true
+		}
+	}
+	return false
+}
+
+// MeetsVersion reports whether the running osquery satisfies a pack or query
+// "version" requirement. Unknown versions are not filtered.
+func MeetsVersion(required string, running Version) bool {
+	if required == "" || running.IsZero() {
+		return true
+	}
+
+	min, err := ParseVersion(required)
+	if err != nil {
+		return true
+	}
+	return running.AtLeast(min)
+}
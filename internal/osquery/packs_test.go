@@ -0,0 +1,109 @@
+package osquery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePack(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPacks(t *testing.T) {
+	dir := t.TempDir()
+	writePack(t, dir, "incident-response.conf", `{
+		"platform": "posix",
+		"discovery": ["SELECT 1 FROM os_version;"],
+		"queries": {
+			"listening_ports": {"query": "SELECT * FROM listening_ports;", "interval": "60"},
+			"crontab": {"query": "SELECT * FROM crontab;", "interval": 300, "removed": false},
+			"last": {"query": "SELECT * FROM last;"}
+		}
+	}`)
+	writePack(t, dir, "notes.txt", "not a pack")
+	if err := os.Mkdir(filepath.Join(dir, "nested.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	packs, err := LoadPacks(dir)
+	if err != nil {
+		t.Fatalf("LoadPacks: %v", err)
+	}
+	if len(packs) != 1 {
+		t.Fatalf("loaded %d packs, want 1", len(packs))
+	}
+
+	pack := packs[0]
+	if pack.Name != "incident-response" || pack.Platform != "posix" || len(pack.Discovery) != 1 {
+		t.Fatalf("pack = %+v", pack)
+	}
+
+	queries := pack.SortedQueries()
+	wantIntervals := map[string]PackInterval{"crontab": 300, "last": defaultPackInterval, "listening_ports": 60}
+	if len(queries) != len(wantIntervals) {
+		t.Fatalf("queries = %+v", queries)
+	}
+	for i, name := range []string{"crontab", "last", "listening_ports"} {
+		if queries[i].Name != name || queries[i].Interval != wantIntervals[name] {
+			t.Errorf("query %d = %s every %d, want %s every %d", i, queries[i].Name, queries[i].Interval, name, wantIntervals[name])
+		}
+	}
+	if removed := queries[0].Removed; removed == nil || *removed {
+		t.Errorf("crontab removed = %v, want explicit false", removed)
+	}
+}
+
+func TestLoadPackErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty-sql.json":    `{"queries": {"nothing": {"query": "  "}}}`,
+		"bad-interval.json": `{"queries": {"q": {"query": "SELECT 1;", "interval": "hourly"}}}`,
+		"broken.json":       `{"queries": `,
+	}
+	for name, content := range tests {
+		dir := t.TempDir()
+		writePack(t, dir, name, content)
+		if _, err := LoadPacks(dir); err == nil {
+			t.Errorf("%s: LoadPacks succeeded, want an error", name)
+		}
+	}
+}
+
+func TestMatchesPlatform(t *testing.T) {
+	tests := []struct {
+		filter string
+		goos   string
+		want   bool
+	}{
+		{"", "linux", true},
+		{"all", "windows", true},
+		{"posix", "darwin", true},
+		{"posix", "windows", false},
+		{"darwin, linux", "linux", true},
+		{"Windows", "windows", true},
+		{"windows", "linux", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesPlatform(tt.filter, tt.goos); got != tt.want {
+			t.Errorf("MatchesPlatform(%q, %q) = %v, want %v", tt.filter, tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestMeetsVersion(t *testing.T) {
+	running := Version{Major: 5, Minor: 11}
+
+	if !MeetsVersion("", running) || !MeetsVersion("5.11.0", running) || !MeetsVersion("4.0", running) {
+		t.Error("MeetsVersion rejected a satisfied requirement")
+	}
+	if MeetsVersion("5.12.0", running) {
+		t.Error("MeetsVersion accepted a newer requirement")
+	}
+	if !MeetsVersion("5.12.0", Version{}) {
+		t.Error("MeetsVersion filtered on an unknown running version")
+	}
+}
@@ -0,0 +1,114 @@
+package osquery
+
+import (
+	"context"
+	"strings"
+)
+
+type SystemdUnit struct {
+	ID           string `json:"id" osquery:"id"`
+	Description  string `json:"description" osquery:"description"`
+	LoadState    string `json:"load_state" osquery:"load_state"`
+	ActiveState  string `json:"active_state" osquery:"active_state"`
+	SubState     string `json:"sub_state" osquery:"sub_state"`
+	FragmentPath string `json:"fragment_path" osquery:"fragment_path"`
+	SourcePath   string `json:"source_path,omitempty" osquery:"source_path"`
+	User         string `json:"user,omitempty" osquery:"user"`
+}
+
+type CronJob struct {
+	Event      string `json:"event,omitempty" osquery:"event"`
+	Minute     string `json:"minute" osquery:"minute"`
+	Hour       string `json:"hour" osquery:"hour"`
+	DayOfMonth string `json:"day_of_month" osquery:"day_of_month"`
+	Month      string `json:"month" osquery:"month"`
+	DayOfWeek  string `json:"day_of_week" osquery:"day_of_week"`
+	Command    string `json:"command" osquery:"command"`
+	Path       string `json:"path" osquery:"path"`
+}
+
+type StartupItem struct {
+	Name     string `json:"name" osquery:"name"`
+	Path     string `json:"path" osquery:"path"`
+	Args     string `json:"args,omitempty" osquery:"args"`
+	Type     string `json:"type" osquery:"type"`
+	Source   string `json:"source" osquery:"source"`
+	Status   string `json:"status" osquery:"status"`
+	Username string `json:"username,omitempty" osquery:"username"`
+}
+
+type Persistence struct {
+	SystemdUnits []SystemdUnit `json:"systemd_units"`
+	CronJobs     []CronJob     `json:"crontab"`
+	StartupItems []StartupItem `json:"startup_items"`
+}
+
+// Collector names used for persistence mechanisms, both in skip records and
+// when deciding whether a previous snapshot can be compared against.
+const (
+	CollectorSystemdUnits = "persistence/systemd_units"
+	CollectorCrontab      = "persistence/crontab"
+	CollectorStartupItems = "persistence/startup_items"
+)
+
+var (
+	systemdUnitsQuery = tableQuery{
+		Table: "systemd_units",
+		Columns: []string{
+			"id", "description", "load_state", "active_state", "sub_state",
+			"fragment_path", "source_path", "user",
+		},
+		Required: []string{"id"},
+	}
+	crontabQuery = tableQuery{
+		Table: "crontab",
+		Columns: []string{
+			"event", "minute", "hour", "day_of_month", "month", "day_of_week", "command", "path",
+		},
+		Required: []string{"command"},
+	}
+	startupItemsQuery = tableQuery{
+		Table:    "startup_items",
+		Columns:  []string{"name", "path", "args", "type", "source", "status", "username"},
+		Required: []string{"name", "path"},
+	}
+)
+
+func (c *OsqueryClient) GetPersistence(ctx context.Context, snapshot *Snapshot) (Persistence, error) {
+	var persistence Persistence
+
+	for _, collector := range []struct {
+		name  string
+		query tableQuery
+		dest  interface{}
+	}{
+		{CollectorSystemdUnits, systemdUnitsQuery, &persistence.SystemdUnits},
+		{CollectorCrontab, crontabQuery, &persistence.CronJobs},
+		{CollectorStartupItems, startupItemsQuery, &persistence.StartupItems},
+	} {
+		reason, err := c.collectTable(ctx, collector.query, collector.dest)
+		if ctx.Err() != nil {
+			return persistence, ctx.Err()
+		}
+		snapshot.skip(ctx, collector.name, reason, err)
+	}
+
+	return persistence, nil
+}
+
+// The Key methods identify an entry across snapshots. State columns such as
+// active_state or status are left out so a unit restarting is not "new".
+
+func (u SystemdUnit) Key() string {
+	return u.ID + "\x00" + u.FragmentPath
+}
+
+func (j CronJob) Key() string {
+	return strings.Join([]string{
+		j.Event, j.Minute, j.Hour, j.DayOfMonth, j.Month, j.DayOfWeek, j.Command, j.Path,
+	}, "\x00")
+}
+
+func (i StartupItem) Key() string {
+	return strings.Join([]string{i.Name, i.Path, i.Args, i.Source, i.Username}, "\x00")
+}
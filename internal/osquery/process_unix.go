@@ -0,0 +1,19 @@
+//go:build !windows
+
+package osquery
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// osqueryi may fork workers, so the whole process group is killed on cancel.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package osquery
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return cmd.Process.Kill()
+	}
+}
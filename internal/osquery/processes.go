@@ -0,0 +1,139 @@
+package osquery
+
+import (
+	"sort"
+)
+
+type Process struct {
+	PID          int64  `json:"pid" osquery:"pid"`
+	Parent       int64  `json:"parent" osquery:"parent"`
+	Name         string `json:"name" osquery:"name"`
+	Path         string `json:"path" osquery:"path"`
+	Cmdline      string `json:"cmdline" osquery:"cmdline"`
+	UID          int64  `json:"uid" osquery:"uid"`
+	StartTime    int64  `json:"start_time" osquery:"start_time"`
+	ResidentSize int64  `json:"resident_size" osquery:"resident_size"`
+	UserTime     int64  `json:"user_time" osquery:"user_time"`
+	SystemTime   int64  `json:"system_time" osquery:"system_time"`
+}
+
+// CPUTime is the total user and system CPU time in milliseconds.
+func (p Process) CPUTime() int64 {
+	return p.UserTime + p.SystemTime
+}
+
+var processesQuery = tableQuery{
+	Table: "processes",
+	Columns: []string{
+		"pid", "parent", "name", "path", "cmdline", "uid",
+		"start_time", "resident_size", "user_time", "system_time",
+	},
+	Required: []string{"pid", "parent", "name"},
+}
+
+const (
+	ProcessSortPID    = "pid"
+	ProcessSortMemory = "memory"
+	ProcessSortCPU    = "cpu"
+)
+
+type ProcessNode struct {
+	Process
+	CPUTime  int64          `json:"cpu_time"`
+	Children []*ProcessNode `json:"children"`
+}
+
+// BuildProcessTree links processes to their parents. Processes whose parent
+// is not in the snapshot (pid 0, or a parent that exited) become roots, as
+// does any process the walk from those roots never reaches: one whose
+// ancestry loops back on itself through pid reuse is cut from its parent so
+// every process appears exactly once.
+func BuildProcessTree(processes []Process) []*ProcessNode {
+	nodes := make([]*ProcessNode, len(processes))
+	byPID := make(map[int64]*ProcessNode, len(processes))
+	for i, process := range processes {
+		nodes[i] = &ProcessNode{
+			Process:  process,
+			CPUTime:  process.CPUTime(),
+			Children: []*ProcessNode{},
+		}
+		if _, ok := byPID[process.PID]; !ok {
+			byPID[process.PID] = nodes[i]
+		}
+	}
+
+	roots := []*ProcessNode{}
+	parents := make(map[*ProcessNode]*ProcessNode, len(nodes))
+	for _, node := range nodes {
+		parent, ok := byPID[node.Parent]
+		if !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+		parents[node] = parent
+	}
+
+	visited := make(map[*ProcessNode]bool, len(nodes))
+	var visit func(*ProcessNode)
+	visit = func(node *ProcessNode) {
+		visited[node] = true
+		for _, child := range node.Children {
+			if !visited[child] {
+				visit(child)
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	for _, node := range nodes {
+		if visited[node] {
+			continue
+		}
+		parent := parents[node]
+		for i, sibling := range parent.Children {
+			if sibling == node {
+				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+				break
+			}
+		}
+		roots = append(roots, node)
+		visit(node)
+	}
+
+	SortProcessTree(roots, ProcessSortPID)
+	return roots
+}
+
+// SortProcessTree orders every level of the tree by the given key, largest
+// first for memory and CPU. Unknown keys sort by pid.
+func SortProcessTree(nodes []*ProcessNode, by string) {
+	less := func(a, b *ProcessNode) bool { return a.PID < b.PID }
+	switch by {
+	case ProcessSortMemory:
+		less = func(a, b *ProcessNode) bool {
+			if a.ResidentSize != b.ResidentSize {
+				return a.ResidentSize > b.ResidentSize
+			}
+			return a.PID < b.PID
+		}
+	case ProcessSortCPU:
+		less = func(a, b *ProcessNode) bool {
+			if a.CPUTime != b.CPUTime {
+				return a.CPUTime > b.CPUTime
+			}
+			return a.PID < b.PID
+		}
+	}
+
+	var walk func([]*ProcessNode)
+	walk = func(level []*ProcessNode) {
+		sort.Slice(level, func(i, j int) bool { return less(level[i], level[j]) })
+		for _, node := range level {
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+}
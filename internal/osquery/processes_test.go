@@ -0,0 +1,84 @@
+package osquery
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatTree renders a tree as "pid(children...)" for comparison.
+func formatTree(nodes []*ProcessNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := fmt.Sprint(node.PID)
+		if len(node.Children) > 0 {
+			part += "(" + formatTree(node.Children) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuildProcessTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []Process
+		want      string
+	}{
+		{
+			name: "tree",
+			processes: []Process{
+				{PID: 0, Parent: 0}, {PID: 1, Parent: 0}, {PID: 2, Parent: 0},
+				{PID: 300, Parent: 1}, {PID: 301, Parent: 300}, {PID: 200, Parent: 1},
+				{PID: 400, Parent: 999},
+			},
+			want: "0(1(200 300(301)) 2) 400",
+		},
+		{
+			name:      "init is its own parent",
+			processes: []Process{{PID: 1, Parent: 1}, {PID: 10, Parent: 1}},
+			want:      "1(10)",
+		},
+		{
+			name: "cycle",
+			processes: []Process{
+				{PID: 1, Parent: 0}, {PID: 100, Parent: 101}, {PID: 101, Parent: 100}, {PID: 102, Parent: 101},
+			},
+			want: "1 100(101(102))",
+		},
+		{
+			name: "reused pid",
+			processes: []Process{
+				{PID: 1, Parent: 0}, {PID: 50, Parent: 1}, {PID: 50, Parent: 60}, {PID: 60, Parent: 50},
+			},
+			want: "1(50(60(50)))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots := BuildProcessTree(tt.processes)
+			if got := formatTree(roots); got != tt.want {
+				t.Fatalf("tree = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortProcessTree(t *testing.T) {
+	roots := BuildProcessTree([]Process{
+		{PID: 1, Parent: 0},
+		{PID: 10, Parent: 1, ResidentSize: 100, UserTime: 5},
+		{PID: 11, Parent: 1, ResidentSize: 300, UserTime: 1},
+		{PID: 12, Parent: 1, ResidentSize: 300, SystemTime: 9},
+	})
+
+	SortProcessTree(roots, ProcessSortMemory)
+	if got, want := formatTree(roots), "1(11 12 10)"; got != want {
+		t.Errorf("by memory = %s, want %s", got, want)
+	}
+	SortProcessTree(roots, ProcessSortCPU)
+	if got, want := formatTree(roots), "1(12 10 11)"; got != want {
+		t.Errorf("by cpu = %s, want %s", got, want)
+	}
+}
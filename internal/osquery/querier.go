@@ -0,0 +1,163 @@
+package osquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+const (
+	BackendOsqueryi  = "osqueryi"
+	BackendSession   = "session"
+	BackendExtension = "extension"
+	BackendFixture   = "fixture"
+
+	DefaultQueryTimeout   = 30 * time.Second
+	DefaultMaxOutputBytes = 32 << 20
+
+	maxStderrBytes = 64 << 10
+)
+
+type Querier interface {
+	Query(ctx context.Context, query string) ([]map[string]interface{}, error)
+}
+
+type QuerierConfig struct {
+	Backend        string
+	BinaryPath     string
+	FixturePath    string
+	SocketPath     string
+	QueryTimeout   time.Duration
+	MaxOutputBytes int
+}
+
+func NewQuerier(cfg QuerierConfig) (Querier, error) {
+	switch cfg.Backend {
+	case "", BackendOsqueryi:
+		return NewExecQuerier(cfg.BinaryPath, cfg.QueryTimeout, cfg.MaxOutputBytes), nil
+	case BackendSession:
+		return NewSessionQuerier(cfg.BinaryPath, cfg.QueryTimeout, cfg.MaxOutputBytes), nil
+	case BackendExtension:
+		return NewExtensionQuerier(cfg.SocketPath, cfg.QueryTimeout), nil
+	case BackendFixture:
+		return NewFixtureQuerier(cfg.FixturePath)
+	default:
+		return nil, fmt.Errorf("unknown osquery backend: %s", cfg.Backend)
+	}
+}
+
+type ExecQuerier struct {
+	binaryPath     string
+	timeout        time.Duration
+	maxOutputBytes int
+}
+
+func NewExecQuerier(binaryPath string, timeout time.Duration, maxOutputBytes int) *ExecQuerier {
+	if binaryPath == "" {
+		binaryPath = "osqueryi"
+	}
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	if maxOutputBytes <= 0 {
+		maxOutputBytes = DefaultMaxOutputBytes
+	}
+	return &ExecQuerier{
+		binaryPath:     binaryPath,
+		timeout:        timeout,
+		maxOutputBytes: maxOutputBytes,
+	}
+}
+
+func (q *ExecQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: q.maxOutputBytes, onOverflow: cancel}
+	stderr := &limitedBuffer{limit: maxStderrBytes}
+
+	cmd := exec.CommandContext(ctx, q.binaryPath, "--json", query)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	setProcessGroup(cmd)
+
+	err := cmd.Run()
+	switch {
+	case stdout.overflowed:
+		return nil, fmt.Errorf("%w (%d bytes) for query %q", ErrOutputTooLarge, q.maxOutputBytes, query)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("%w after %s: %s", ErrQueryTimeout, q.timeout, query)
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil:
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return nil, &ExitError{
+			Query:    query,
+			ExitCode: exitCode,
+			Stderr:   string(bytes.TrimSpace(stderr.Bytes())),
+			Err:      err,
+		}
+	}
+
+	rows, err := decodeRows(stdout.Bytes())
+	if err != nil {
+		return nil, &ParseError{Query: query, Err: err}
+	}
+
+	return rows, nil
+}
+
+// decodeRows parses osqueryi's JSON output. Numbers stay json.Number so
+// convertValue can parse them without rounding through float64.
+func decodeRows(data []byte) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON rows")
+	}
+	return rows, nil
+}
+
+// limitedBuffer deliberately does not embed bytes.Buffer: io.Copy would use its
+// ReadFrom and bypass the size check in Write.
+type limitedBuffer struct {
+	buf        bytes.Buffer
+	limit      int
+	overflowed bool
+	onOverflow func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflowed {
+		return len(p), nil
+	}
+
+	if b.buf.Len()+len(p) > b.limit {
+		b.buf.Write(p[:b.limit-b.buf.Len()])
+		b.overflowed = true
+		if b.onOverflow != nil {
+			b.onOverflow()
+		}
+		return len(p), nil
+	}
+
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
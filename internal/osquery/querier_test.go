@@ -0,0 +1,95 @@
+//go:build !windows
+
+package osquery
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFakeOsqueryi writes a shell script standing in for the osqueryi
+// binary and returns its path.
+func writeFakeOsqueryi(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "osqueryi")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeExecOsqueryi answers `osqueryi --json <query>` by the query text.
+const fakeExecOsqueryi = `
+case "$2" in
+"SELECT version FROM osquery_info;")
+	echo '[{"version":"5.11.0"}]' ;;
+"SELECT * FROM nope;")
+	echo 'Error: no such table: nope' >&2
+	exit 1 ;;
+"SELECT garbage;")
+	echo 'not json' ;;
+"SELECT big;")
+	head -c 4096 /dev/zero | tr '\0' 'x' ;;
+"SELECT sleep;")
+	exec sleep 10 ;;
+esac
+`
+
+func TestExecQuerierQuery(t *testing.T) {
+	q := NewExecQuerier(writeFakeOsqueryi(t, fakeExecOsqueryi), 5*time.Second, 1024)
+
+	rows, err := q.Query(context.Background(), "SELECT version FROM osquery_info;")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	want := []map[string]interface{}{{"version": "5.11.0"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestExecQuerierErrors(t *testing.T) {
+	q := NewExecQuerier(writeFakeOsqueryi(t, fakeExecOsqueryi), 200*time.Millisecond, 1024)
+	ctx := context.Background()
+
+	_, err := q.Query(ctx, "SELECT * FROM nope;")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 1 || !strings.Contains(exitErr.Stderr, "no such table") {
+		t.Errorf("failing query: err = %v, want ExitError with stderr", err)
+	}
+
+	_, err = q.Query(ctx, "SELECT garbage;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("garbage output: err = %v, want ParseError", err)
+	}
+
+	if _, err := q.Query(ctx, "SELECT big;"); !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("large output: err = %v, want %v", err, ErrOutputTooLarge)
+	}
+
+	start := time.Now()
+	if _, err := q.Query(ctx, "SELECT sleep;"); !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("slow query: err = %v, want %v", err, ErrQueryTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("slow query returned after %s, want the timeout to kill it", elapsed)
+	}
+}
+
+func TestExecQuerierCanceled(t *testing.T) {
+	q := NewExecQuerier(writeFakeOsqueryi(t, fakeExecOsqueryi), 5*time.Second, 1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	if _, err := q.Query(ctx, "SELECT sleep;"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
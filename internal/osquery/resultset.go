@@ -0,0 +1,329 @@
+package osquery
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ColumnText           = "TEXT"
+	ColumnInteger        = "INTEGER"
+	ColumnBigInt         = "BIGINT"
+	ColumnUnsignedBigInt = "UNSIGNED_BIGINT"
+	ColumnDouble         = "DOUBLE"
+	ColumnBlob           = "BLOB"
+)
+
+var tableRefPattern = regexp.MustCompile(`(?i)\b(?:from|join)\s+([a-z_][a-z0-9_]*)`)
+
+// ColumnTyper is implemented by backends that can ask osquery for the result
+// columns of a query directly.
+type ColumnTyper interface {
+	QueryColumns(ctx context.Context, query string) ([]Column, error)
+}
+
+type Row map[string]interface{}
+
+type ResultSet struct {
+	Columns []Column `json:"columns"`
+	Rows    []Row    `json:"rows"`
+}
+
+// MarshalJSON writes blobs as text, the way osquery prints them, rather than
+// base64 so stored rows compare equal to freshly queried ones.
+func (r Row) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(r))
+	for name, value := range r {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		out[name] = value
+	}
+	return json.Marshal(out)
+}
+
+func (r Row) String(column string) string {
+	switch v := r[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case json.Number:
+		return v.String()
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func (r Row) Int64(column string) int64 {
+	switch v := r[column].(type) {
+	case int64:
+		return v
+	case uint64:
+		if v > math.MaxInt64 {
+			return math.MaxInt64
+		}
+		return int64(v)
+	case float64:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
+
+func (r Row) Float64(column string) float64 {
+	switch v := r[column].(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+func (c *OsqueryClient) Query(ctx context.Context, query string) (ResultSet, error) {
+	raw, err := c.querier.Query(ctx, query)
+	if err != nil {
+		return ResultSet{}, err
+	}
+
+	columns := c.resolveColumns(ctx, query, raw)
+	types := make(map[string]string, len(columns))
+	for _, column := range columns {
+		types[column.Name] = column.Type
+	}
+
+	rows := make([]Row, 0, len(raw))
+	for _, rawRow := range raw {
+		row := make(Row, len(rawRow))
+		for name, value := range rawRow {
+			row[name] = convertValue(types[name], value)
+		}
+		rows = append(rows, row)
+	}
+
+	return ResultSet{Columns: columns, Rows: rows}, nil
+}
+
+func (c *OsqueryClient) resolveColumns(ctx context.Context, query string, raw []map[string]interface{}) []Column {
+	if typer, ok := c.querier.(ColumnTyper); ok {
+		if columns, err := typer.QueryColumns(ctx, query); err == nil && len(columns) > 0 {
+			return columns
+		}
+	}
+
+	known := make(map[string]Column)
+	var order []string
+	for _, match := range tableRefPattern.FindAllStringSubmatch(query, -1) {
+		for _, column := range c.tableColumns(ctx, match[1]) {
+			if _, seen := known[column.Name]; !seen {
+				known[column.Name] = column
+				order = append(order, column.Name)
+			}
+		}
+	}
+
+	present := make(map[string]interface{})
+	for _, rawRow := range raw {
+		for name, value := range rawRow {
+			if _, seen := present[name]; !seen || present[name] == nil {
+				present[name] = value
+			}
+		}
+	}
+
+	columns := make([]Column, 0, len(present))
+	for _, name := range order {
+		if _, ok := present[name]; ok {
+			columns = append(columns, known[name])
+			delete(present, name)
+		}
+	}
+
+	extra := make([]string, 0, len(present))
+	for name := range present {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		columns = append(columns, Column{Name: name, Type: inferType(present[name])})
+	}
+
+	return columns
+}
+
+func (c *OsqueryClient) tableColumns(ctx context.Context, table string) []Column {
+	c.mu.Lock()
+	columns, ok := c.tableInfo[table]
+	missing := len(c.tables) > 0 && !c.tables[table]
+	c.mu.Unlock()
+	if ok || missing {
+		return columns
+	}
+
+	rows, err := c.querier.Query(ctx, "PRAGMA table_info("+table+");")
+	if err != nil {
+		// Failed lookups are cached as empty (unknown) so they are not repeated.
+		if ctx.Err() != nil {
+			return nil
+		}
+		rows = nil
+	}
+
+	columns = make([]Column, 0, len(rows))
+	for _, row := range rows {
+		name, _ := row["name"].(string)
+		columnType, _ := row["type"].(string)
+		if name != "" {
+			columns = append(columns, Column{Name: name, Type: strings.ToUpper(columnType)})
+		}
+	}
+
+	c.mu.Lock()
+	c.tableInfo[table] = columns
+	c.mu.Unlock()
+
+	return columns
+}
+
+func inferType(value interface{}) string {
+	if n, ok := value.(json.Number); ok {
+		if _, err := n.Int64(); err == nil {
+			return ColumnBigInt
+		}
+		if _, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return ColumnUnsignedBigInt
+		}
+		return ColumnDouble
+	}
+	if f, ok := value.(float64); ok {
+		if f == float64(int64(f)) {
+			return ColumnBigInt
+		}
+		return ColumnDouble
+	}
+	return ColumnText
+}
+
+// convertValue maps osquery's textual output onto Go types. osquery reports
+// NULL as an empty string, so numeric columns turn "" into nil.
+// UNSIGNED_BIGINT becomes uint64 so values above MaxInt64 keep every digit.
+func convertValue(columnType string, value interface{}) interface{} {
+	if n, ok := value.(json.Number); ok {
+		// Parse the digits as written; going through float64 would round
+		// anything above 2^53.
+		switch columnType {
+		case ColumnUnsignedBigInt, ColumnInteger, ColumnBigInt, ColumnDouble:
+			value = n.String()
+		case ColumnText:
+			return n.String()
+		default:
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+			if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+				return u
+			}
+			f, err := n.Float64()
+			if err != nil {
+				return n.String()
+			}
+			value = f
+		}
+	}
+
+	switch columnType {
+	case ColumnUnsignedBigInt:
+		switch v := value.(type) {
+		case float64:
+			if v >= 0 {
+				return uint64(v)
+			}
+			return int64(v)
+		case string:
+			if v == "" {
+				return nil
+			}
+			if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+				return n
+			}
+			// Some tables report -1 for unknown in unsigned columns.
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n
+			}
+			return v
+		}
+	case ColumnInteger, ColumnBigInt:
+		switch v := value.(type) {
+		case float64:
+			return int64(v)
+		case string:
+			if v == "" {
+				return nil
+			}
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return int64(f)
+			}
+			return v
+		}
+	case ColumnDouble:
+		switch v := value.(type) {
+		case float64:
+			return v
+		case string:
+			if v == "" {
+				return nil
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+			return v
+		}
+	case ColumnBlob:
+		if v, ok := value.(string); ok {
+			return []byte(v)
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if columnType == ColumnText {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	}
+	return value
+}
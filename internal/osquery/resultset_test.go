@@ -0,0 +1,206 @@
+package osquery
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newFixtureClient(t *testing.T, fixtures map[string][]map[string]interface{}) *OsqueryClient {
+	t.Helper()
+
+	data, err := json.Marshal(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, err := NewFixtureQuerier(path)
+	if err != nil {
+		t.Fatalf("NewFixtureQuerier: %v", err)
+	}
+	return NewOsqueryClient(fixture)
+}
+
+func TestQueryConvertsColumnTypes(t *testing.T) {
+	query := "SELECT * FROM process_memory;"
+	c := newFixtureClient(t, map[string][]map[string]interface{}{
+		"PRAGMA table_info(process_memory);": {
+			{"name": "pid", "type": "integer"},
+			{"name": "start", "type": "UNSIGNED_BIGINT"},
+			{"name": "offset", "type": "UNSIGNED_BIGINT"},
+			{"name": "rss", "type": "BIGINT"},
+			{"name": "ratio", "type": "DOUBLE"},
+			{"name": "path", "type": "TEXT"},
+			{"name": "blob", "type": "BLOB"},
+		},
+		query: {
+			{
+				"pid": "42", "start": "18446744073709551615", "offset": "-1", "rss": "",
+				"ratio": "0.5", "path": "/bin/sh", "blob": "raw", "extra": "x",
+			},
+		},
+	})
+
+	result, err := c.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	want := Row{
+		"pid":    int64(42),
+		"start":  uint64(18446744073709551615),
+		"offset": int64(-1),
+		"rss":    nil,
+		"ratio":  0.5,
+		"path":   "/bin/sh",
+		"blob":   []byte("raw"),
+		"extra":  "x",
+	}
+	if len(result.Rows) != 1 || !reflect.DeepEqual(result.Rows[0], want) {
+		t.Fatalf("rows = %#v, want %#v", result.Rows, want)
+	}
+
+	wantColumns := []Column{
+		{Name: "pid", Type: ColumnInteger},
+		{Name: "start", Type: ColumnUnsignedBigInt},
+		{Name: "offset", Type: ColumnUnsignedBigInt},
+		{Name: "rss", Type: ColumnBigInt},
+		{Name: "ratio", Type: ColumnDouble},
+		{Name: "path", Type: ColumnText},
+		{Name: "blob", Type: ColumnBlob},
+		{Name: "extra", Type: ColumnText},
+	}
+	if !reflect.DeepEqual(result.Columns, wantColumns) {
+		t.Fatalf("columns = %v, want %v", result.Columns, wantColumns)
+	}
+}
+
+// Values given as JSON numbers rather than strings keep every digit, even
+// above 2^53 where float64 would round them.
+func TestQueryConvertsJSONNumbers(t *testing.T) {
+	query := "SELECT * FROM process_memory;"
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	fixtures := `{
+		"PRAGMA table_info(process_memory);": [
+			{"name": "start", "type": "UNSIGNED_BIGINT"},
+			{"name": "rss", "type": "BIGINT"},
+			{"name": "ratio", "type": "DOUBLE"},
+			{"name": "inode", "type": "TEXT"}
+		],
+		"SELECT * FROM process_memory;": [
+			{"start": 18446744073709551615, "rss": 9007199254740993, "ratio": 0.5,
+			 "inode": 9007199254740993, "extra": 9007199254740993}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(fixtures), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fixture, err := NewFixtureQuerier(path)
+	if err != nil {
+		t.Fatalf("NewFixtureQuerier: %v", err)
+	}
+
+	result, err := NewOsqueryClient(fixture).Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	want := Row{
+		"start": uint64(18446744073709551615),
+		"rss":   int64(9007199254740993),
+		"ratio": 0.5,
+		"inode": "9007199254740993",
+		"extra": int64(9007199254740993),
+	}
+	if len(result.Rows) != 1 || !reflect.DeepEqual(result.Rows[0], want) {
+		t.Fatalf("rows = %#v, want %#v", result.Rows, want)
+	}
+}
+
+func TestResultSetDecode(t *testing.T) {
+	type target struct {
+		Name    string  `osquery:"name"`
+		PID     int64   `osquery:"pid"`
+		Start   uint64  `osquery:"start"`
+		Ratio   float64 `osquery:"ratio"`
+		Enabled bool    `osquery:"enabled"`
+		Skipped string  `osquery:"-"`
+		Path    string
+	}
+
+	rs := ResultSet{Rows: []Row{
+		{
+			"name": "sshd", "pid": int64(7), "start": uint64(18446744073709551615),
+			"ratio": 1.25, "enabled": "1", "Skipped": "no", "path": "/usr/sbin/sshd",
+		},
+		{"name": "cron", "pid": nil, "enabled": "0"},
+	}}
+
+	var got []target
+	if err := rs.Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := []target{
+		{Name: "sshd", PID: 7, Start: 18446744073709551615, Ratio: 1.25, Enabled: true, Path: "/usr/sbin/sshd"},
+		{Name: "cron"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode = %+v, want %+v", got, want)
+	}
+}
+
+func TestResultSetDecodeErrors(t *testing.T) {
+	type small struct {
+		Value int8 `osquery:"value"`
+	}
+	type flag struct {
+		Value bool `osquery:"value"`
+	}
+
+	var smalls []small
+	if err := (ResultSet{Rows: []Row{{"value": int64(300)}}}).Decode(&smalls); err == nil {
+		t.Error("Decode accepted a value overflowing int8")
+	}
+	var flags []flag
+	if err := (ResultSet{Rows: []Row{{"value": "maybe"}}}).Decode(&flags); err == nil {
+		t.Error("Decode accepted an invalid boolean")
+	}
+	if err := (ResultSet{}).Decode(flags); err == nil {
+		t.Error("Decode accepted a non-pointer destination")
+	}
+}
+
+func TestRowAccessors(t *testing.T) {
+	row := Row{
+		"big":    uint64(18446744073709551615),
+		"number": json.Number("9007199254740993"),
+		"float":  2.5,
+		"text":   "12",
+	}
+
+	if got := row.String("big"); got != "18446744073709551615" {
+		t.Errorf("String(big) = %q", got)
+	}
+	if got := row.Int64("big"); got != 1<<63-1 {
+		t.Errorf("Int64(big) = %d, want MaxInt64", got)
+	}
+	if got := row.Int64("number"); got != 9007199254740993 {
+		t.Errorf("Int64(number) = %d", got)
+	}
+	if got := row.String("float"); got != "2.5" {
+		t.Errorf("String(float) = %q", got)
+	}
+	if got := row.Int64("text"); got != 12 {
+		t.Errorf("Int64(text) = %d", got)
+	}
+	if got := row.Float64("missing"); got != 0 {
+		t.Errorf("Float64(missing) = %v", got)
+	}
+}
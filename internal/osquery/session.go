@@ -0,0 +1,280 @@
+package osquery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const frameColumn = "osquery_mvp_frame"
+
+// glogLinePattern matches the log lines osquery writes to stderr, such as
+// "W1017 12:00:00.123456  4242 virtual_table.cpp:123] ...".
+var glogLinePattern = regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ \S+:\d+\] `)
+
+// SessionQuerier keeps one osqueryi shell alive and feeds it statements over
+// stdin. Every statement is followed by two sentinels: a SELECT of an unknown
+// column, whose error marks the end of the statement's stderr, and a SELECT
+// of the same token, which marks the end of its stdout. osqueryi runs the
+// statements in order and flushes stdout before reading the next line, so
+// once the stdout sentinel arrives everything the statement wrote is in the
+// pipes.
+type SessionQuerier struct {
+	binaryPath     string
+	timeout        time.Duration
+	maxOutputBytes int
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	stdin  io.WriteCloser
+	stdout chan string
+	stderr chan string
+	seq    uint64
+}
+
+func NewSessionQuerier(binaryPath string, timeout time.Duration, maxOutputBytes int) *SessionQuerier {
+	if binaryPath == "" {
+		binaryPath = "osqueryi"
+	}
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	if maxOutputBytes <= 0 {
+		maxOutputBytes = DefaultMaxOutputBytes
+	}
+	return &SessionQuerier{
+		binaryPath:     binaryPath,
+		timeout:        timeout,
+		maxOutputBytes: maxOutputBytes,
+	}
+}
+
+func (s *SessionQuerier) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.queryLocked(ctx, query)
+	if errors.Is(err, ErrSessionClosed) && ctx.Err() == nil {
+		rows, err = s.queryLocked(ctx, query)
+	}
+	return rows, err
+}
+
+func (s *SessionQuerier) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopLocked()
+	return nil
+}
+
+func (s *SessionQuerier) queryLocked(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	if s.cmd == nil {
+		if err := s.startLocked(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	s.seq++
+	token := fmt.Sprintf("%s_%d_%d", frameColumn, s.cmd.Process.Pid, s.seq)
+	statement := strings.TrimRight(strings.TrimSpace(query), ";")
+	request := fmt.Sprintf("%s;\nSELECT %s;\nSELECT '%s' AS %s;\n", statement, token, token, frameColumn)
+
+	if _, err := io.WriteString(s.stdin, request); err != nil {
+		s.stopLocked()
+		return nil, fmt.Errorf("%w: %v", ErrSessionClosed, err)
+	}
+
+	stdout, err := s.readUntil(ctx, s.stdout, token, s.maxOutputBytes, query)
+	if err != nil {
+		return nil, err
+	}
+
+	frameStart := len(stdout) - 1
+	for i := len(stdout) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(stdout[i]), "[") {
+			frameStart = i
+			break
+		}
+	}
+
+	// Drain the rest of the sentinel's JSON array so the next frame starts clean.
+	last := strings.TrimSpace(stdout[len(stdout)-1])
+	for !strings.HasSuffix(last, "]") {
+		line, err := s.readLine(ctx, s.stdout, query)
+		if err != nil {
+			return nil, err
+		}
+		last = strings.TrimSpace(line)
+	}
+
+	stderr, err := s.readUntil(ctx, s.stderr, token, maxStderrBytes, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFrame(query, strings.Join(stdout[:frameStart], ""), stderr[:len(stderr)-1])
+}
+
+// readUntil reads lines up to and including the first one containing token.
+func (s *SessionQuerier) readUntil(ctx context.Context, lines <-chan string, token string, limit int, query string) ([]string, error) {
+	var read []string
+	size := 0
+	for {
+		line, err := s.readLine(ctx, lines, query)
+		if err != nil {
+			return nil, err
+		}
+
+		size += len(line)
+		if size > limit {
+			s.stopLocked()
+			return nil, fmt.Errorf("%w (%d bytes) for query %q", ErrOutputTooLarge, limit, query)
+		}
+
+		read = append(read, line)
+		if strings.Contains(line, token) {
+			return read, nil
+		}
+	}
+}
+
+func (s *SessionQuerier) readLine(ctx context.Context, lines <-chan string, query string) (string, error) {
+	select {
+	case <-ctx.Done():
+		s.stopLocked()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("%w after %s: %s", ErrQueryTimeout, s.timeout, query)
+		}
+		return "", ctx.Err()
+	case line, ok := <-lines:
+		if !ok {
+			s.stopLocked()
+			return "", ErrSessionClosed
+		}
+		return line, nil
+	}
+}
+
+// parseFrame decodes a statement's stdout. Anything on stderr other than
+// osquery's own log lines is the shell reporting that the statement failed.
+func parseFrame(query, output string, stderr []string) ([]map[string]interface{}, error) {
+	var messages []string
+	for _, line := range stderr {
+		line = strings.TrimSpace(line)
+		if line != "" && !glogLinePattern.MatchString(line) {
+			messages = append(messages, line)
+		}
+	}
+	if len(messages) > 0 {
+		return nil, &QueryError{Query: query, Message: strings.Join(messages, "\n")}
+	}
+
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return []map[string]interface{}{}, nil
+	}
+
+	rows, err := decodeRows([]byte(output))
+	if err != nil {
+		return nil, &ParseError{Query: query, Err: err}
+	}
+	return rows, nil
+}
+
+func (s *SessionQuerier) startLocked() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, s.binaryPath, "--json")
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open osquery session stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open osquery session stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open osquery session stderr: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("failed to start osquery session: %w", err)
+	}
+
+	// Wait closes the pipes, so it only runs once both readers hit EOF.
+	var readers sync.WaitGroup
+	readers.Add(2)
+	s.cmd = cmd
+	s.cancel = cancel
+	s.stdin = stdin
+	s.stdout = readLines(stdout, &readers)
+	s.stderr = readLines(stderr, &readers)
+
+	go func() {
+		readers.Wait()
+		cmd.Wait()
+	}()
+	return nil
+}
+
+// readLines hands the lines of r over a channel that is closed at EOF.
+func readLines(r io.Reader, done *sync.WaitGroup) chan string {
+	lines := make(chan string, 64)
+	go func() {
+		defer done.Done()
+		defer close(lines)
+
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				lines <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+func (s *SessionQuerier) stopLocked() {
+	if s.cmd == nil {
+		return
+	}
+
+	s.stdin.Close()
+	s.cancel()
+
+	// Unblock the reader goroutines if they are waiting to hand over a line.
+	for _, lines := range []chan string{s.stdout, s.stderr} {
+		go func(lines chan string) {
+			for range lines {
+			}
+		}(lines)
+	}
+
+	s.cmd = nil
+	s.cancel = nil
+	s.stdin = nil
+	s.stdout = nil
+	s.stderr = nil
+}
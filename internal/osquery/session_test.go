@@ -0,0 +1,178 @@
+//go:build !windows
+
+package osquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeSessionOsqueryi reads statements line by line like `osqueryi --json`:
+// results go to stdout as a JSON array and errors to stderr.
+const fakeSessionOsqueryi = `
+while IFS= read -r line; do
+	case "$line" in
+	"SELECT '"*)
+		token=${line#SELECT \'}
+		token=${token%%\'*}
+		printf '[\n  {"osquery_mvp_frame":"%s"}\n]\n' "$token" ;;
+	"SELECT osquery_mvp_frame_"*)
+		token=${line#SELECT }
+		echo "Error: no such column: ${token%;}" >&2 ;;
+	"SELECT version FROM osquery_info;")
+		printf '[\n  {"version":"5.11.0"}\n]\n' ;;
+	"SELECT warn;")
+		echo 'W1017 12:00:00.123456  4242 virtual_table.cpp:123] Table users is event-based but events are disabled' >&2
+		printf '[\n  {"value":"1"}\n]\n' ;;
+	"SELECT echo "*)
+		n=${line#SELECT echo }
+		printf '[\n  {"n":"%s"}\n]\n' "${n%;}" ;;
+	"SELECT pid;")
+		printf '[\n  {"pid":"%s"}\n]\n' "$$" ;;
+	"SELECT * FROM nope;")
+		echo 'Error: no such table: nope' >&2 ;;
+	"SELECT die;")
+		exit 1 ;;
+	"SELECT sleep;")
+		sleep 10 ;;
+	esac
+done
+`
+
+func newTestSessionQuerier(t *testing.T, timeout time.Duration) *SessionQuerier {
+	t.Helper()
+
+	s := NewSessionQuerier(writeFakeOsqueryi(t, fakeSessionOsqueryi), timeout, 1<<20)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSessionQuerierQuery(t *testing.T) {
+	s := newTestSessionQuerier(t, 5*time.Second)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  []map[string]interface{}
+	}{
+		{"SELECT version FROM osquery_info;", []map[string]interface{}{{"version": "5.11.0"}}},
+		{"SELECT warn;", []map[string]interface{}{{"value": "1"}}},
+		{"SELECT nothing;", []map[string]interface{}{}},
+		{"SELECT version FROM osquery_info", []map[string]interface{}{{"version": "5.11.0"}}},
+	}
+	for _, tt := range tests {
+		rows, err := s.Query(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if !reflect.DeepEqual(rows, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.query, rows, tt.want)
+		}
+	}
+}
+
+func TestSessionQuerierQueryError(t *testing.T) {
+	s := newTestSessionQuerier(t, 5*time.Second)
+	ctx := context.Background()
+
+	_, err := s.Query(ctx, "SELECT * FROM nope;")
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Message != "Error: no such table: nope" {
+		t.Fatalf("err = %v, want QueryError", err)
+	}
+
+	// The session stays usable after a failed statement.
+	if _, err := s.Query(ctx, "SELECT version FROM osquery_info;"); err != nil {
+		t.Fatalf("Query after error: %v", err)
+	}
+}
+
+func sessionPid(t *testing.T, s *SessionQuerier) string {
+	t.Helper()
+
+	rows, err := s.Query(context.Background(), "SELECT pid;")
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("SELECT pid: rows = %v, err = %v", rows, err)
+	}
+	return rows[0]["pid"].(string)
+}
+
+func TestSessionQuerierRestart(t *testing.T) {
+	s := newTestSessionQuerier(t, 5*time.Second)
+
+	if _, err := s.Query(context.Background(), "SELECT die;"); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("err = %v, want %v", err, ErrSessionClosed)
+	}
+
+	// A shell that dies between queries is restarted and the query retried.
+	pid := sessionPid(t, s)
+	n, err := strconv.Atoi(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(n, syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if got := sessionPid(t, s); got == pid {
+		t.Fatalf("pid %s still answering after it was killed", got)
+	}
+}
+
+func TestSessionQuerierTimeout(t *testing.T) {
+	s := newTestSessionQuerier(t, 200*time.Millisecond)
+
+	start := time.Now()
+	if _, err := s.Query(context.Background(), "SELECT sleep;"); !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("err = %v, want %v", err, ErrQueryTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("slow query returned after %s, want the timeout to kill it", elapsed)
+	}
+
+	if _, err := s.Query(context.Background(), "SELECT version FROM osquery_info;"); err != nil {
+		t.Fatalf("Query after timeout: %v", err)
+	}
+}
+
+func TestSessionQuerierConcurrent(t *testing.T) {
+	s := newTestSessionQuerier(t, 5*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			want := fmt.Sprint(i)
+			rows, err := s.Query(context.Background(), "SELECT echo "+want+";")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(rows) != 1 || rows[0]["n"] != want {
+				errs <- fmt.Errorf("query %d got rows %v", i, rows)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	var failures []string
+	for err := range errs {
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		t.Fatal(strings.Join(failures, "\n"))
+	}
+}
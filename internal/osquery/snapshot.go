@@ -0,0 +1,122 @@
+package osquery
+
+import (
+	"context"
+	"fmt"
+)
+
+type Snapshot struct {
+	SystemInfo     SystemInfoResult
+	Apps           []InstalledApp
+	ListeningPorts []ListeningPort
+	Accounts       Accounts
+	Processes      []Process
+	Persistence    Persistence
+	Certificates   []Certificate
+	FilePatterns   []string
+	Files          []FileEntry
+	Mounts         []Mount
+	KernelModules  []KernelModule
+	SystemControls []SystemControl
+	Docker         Docker
+	Extensions     []Extension
+	Skipped        []SkippedCollector
+}
+
+// CollectSnapshot runs every collector. System info and installed apps are
+// required; the remaining collectors are recorded as skipped when the host
+// cannot provide them or they fail, so one bad table does not lose the run.
+func (c *OsqueryClient) CollectSnapshot(ctx context.Context) (Snapshot, error) {
+	var snapshot Snapshot
+	var err error
+
+	snapshot.SystemInfo, err = c.GetSystemInfo(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Apps, snapshot.Skipped, err = c.GetInstalledApps(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+
+	ports, reason, err := c.GetListeningPorts(ctx)
+	snapshot.ListeningPorts = ports
+	snapshot.skip(ctx, "network/listening_ports", reason, err)
+
+	snapshot.Accounts, err = c.GetAccounts(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	reason, err = c.collectTable(ctx, processesQuery, &snapshot.Processes)
+	snapshot.skip(ctx, "processes", reason, err)
+
+	snapshot.Persistence, err = c.GetPersistence(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Certificates, err = c.GetCertificates(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Files, err = c.GetFileIntegrity(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	reason, err = c.collectTable(ctx, mountsQuery, &snapshot.Mounts)
+	snapshot.skip(ctx, "mounts", reason, err)
+
+	snapshot.KernelModules, reason, err = c.GetKernelModules(ctx)
+	snapshot.skip(ctx, "kernel/modules", reason, err)
+
+	snapshot.SystemControls, reason, err = c.GetSystemControls(ctx)
+	snapshot.skip(ctx, "kernel/system_controls", reason, err)
+
+	snapshot.Docker, err = c.GetDocker(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Extensions, err = c.GetExtensions(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, ctx.Err()
+}
+
+// collectTable runs a single-table collector into dest, a pointer to a slice
+// of structs. A non-empty reason means the host cannot provide the table.
+func (c *OsqueryClient) collectTable(ctx context.Context, q tableQuery, dest interface{}) (string, error) {
+	query, reason := c.buildQuery(ctx, q)
+	if reason != "" {
+		return reason, nil
+	}
+
+	result, err := c.Query(ctx, query)
+	if err != nil {
+		if isMissingSchemaError(err) {
+			return err.Error(), nil
+		}
+		return "", fmt.Errorf("failed to query %s: %w", q.Table, err)
+	}
+
+	if err := result.Decode(dest); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", q.Table, err)
+	}
+	return "", nil
+}
+
+func (s *Snapshot) skip(ctx context.Context, collector, reason string, err error) {
+	switch {
+	case err != nil && ctx.Err() == nil:
+		reason = fmt.Sprintf("collection failed: %v", err)
+	case reason == "":
+		return
+	}
+	s.Skipped = append(s.Skipped, SkippedCollector{Name: collector, Reason: reason})
+}
@@ -0,0 +1,336 @@
+package osquery
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Minimal Thrift binary protocol codec, covering what the osquery extension
+// manager's query and getQueryColumns calls need.
+
+const (
+	thriftVersion1     = 0x80010000
+	thriftVersionMask  = 0xffff0000
+	thriftMessageCall  = 1
+	thriftMessageReply = 2
+	thriftMessageError = 3
+
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+
+	maxThriftStringBytes    = 64 << 20
+	maxThriftContainerItems = 1 << 20
+	maxThriftPrealloc       = 1024
+	maxThriftDepth          = 64
+)
+
+var errThriftMalformed = errors.New("malformed thrift message")
+
+type thriftWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (t *thriftWriter) write(p []byte) {
+	if t.err == nil {
+		_, t.err = t.w.Write(p)
+	}
+}
+
+func (t *thriftWriter) writeByte(v byte) {
+	t.write([]byte{v})
+}
+
+func (t *thriftWriter) writeI16(v int16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(v))
+	t.write(buf[:])
+}
+
+func (t *thriftWriter) writeI32(v int32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	t.write(buf[:])
+}
+
+func (t *thriftWriter) writeString(v string) {
+	t.writeI32(int32(len(v)))
+	t.write([]byte(v))
+}
+
+func (t *thriftWriter) writeMessageBegin(name string, messageType byte, seqID int32) {
+	t.writeI32(int32(uint32(thriftVersion1) | uint32(messageType)))
+	t.writeString(name)
+	t.writeI32(seqID)
+}
+
+func (t *thriftWriter) writeFieldBegin(fieldType byte, id int16) {
+	t.writeByte(fieldType)
+	t.writeI16(id)
+}
+
+func (t *thriftWriter) writeFieldStop() {
+	t.writeByte(thriftStop)
+}
+
+func (t *thriftWriter) flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.w.Flush()
+}
+
+type thriftReader struct {
+	r *bufio.Reader
+}
+
+func (t *thriftReader) readByte() (byte, error) {
+	return t.r.ReadByte()
+}
+
+func (t *thriftReader) readI16() (int16, error) {
+	var buf [2]byte
+	if _, err := io.ReadFull(t.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(buf[:])), nil
+}
+
+func (t *thriftReader) readI32() (int32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(t.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(buf[:])), nil
+}
+
+func (t *thriftReader) readI64() (int64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(t.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(buf[:])), nil
+}
+
+func (t *thriftReader) readString() (string, error) {
+	size, err := t.readI32()
+	if err != nil {
+		return "", err
+	}
+	if size < 0 || size > maxThriftStringBytes {
+		return "", fmt.Errorf("%w: string length %d", errThriftMalformed, size)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(t.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (t *thriftReader) readMessageBegin() (name string, messageType byte, seqID int32, err error) {
+	header, err := t.readI32()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if uint32(header)&thriftVersionMask != thriftVersion1 {
+		return "", 0, 0, fmt.Errorf("%w: unsupported protocol version %#x", errThriftMalformed, uint32(header))
+	}
+
+	if name, err = t.readString(); err != nil {
+		return "", 0, 0, err
+	}
+	if seqID, err = t.readI32(); err != nil {
+		return "", 0, 0, err
+	}
+	return name, byte(header & 0xff), seqID, nil
+}
+
+func (t *thriftReader) readFieldBegin() (fieldType byte, id int16, err error) {
+	if fieldType, err = t.readByte(); err != nil || fieldType == thriftStop {
+		return fieldType, 0, err
+	}
+	id, err = t.readI16()
+	return fieldType, id, err
+}
+
+// readStruct walks the fields of a struct, handing each to fn. Fields that fn
+// does not consume must be skipped by returning handled=false.
+func (t *thriftReader) readStruct(fn func(fieldType byte, id int16) (handled bool, err error)) error {
+	for {
+		fieldType, id, err := t.readFieldBegin()
+		if err != nil {
+			return err
+		}
+		if fieldType == thriftStop {
+			return nil
+		}
+
+		handled, err := fn(fieldType, id)
+		if err != nil {
+			return err
+		}
+		if !handled {
+			if err := t.skip(fieldType, 0); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *thriftReader) readStringMap() (map[string]string, error) {
+	keyType, err := t.readByte()
+	if err != nil {
+		return nil, err
+	}
+	valueType, err := t.readByte()
+	if err != nil {
+		return nil, err
+	}
+	size, err := t.readI32()
+	if err != nil {
+		return nil, err
+	}
+	if keyType != thriftString || valueType != thriftString {
+		return nil, fmt.Errorf("%w: expected map<string,string>", errThriftMalformed)
+	}
+	if err := checkContainerSize(size); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, min(size, maxThriftPrealloc))
+	for i := int32(0); i < size; i++ {
+		key, err := t.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := t.readString()
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (t *thriftReader) readStringMapList() ([]map[string]string, error) {
+	elemType, err := t.readByte()
+	if err != nil {
+		return nil, err
+	}
+	size, err := t.readI32()
+	if err != nil {
+		return nil, err
+	}
+	if elemType != thriftMap {
+		return nil, fmt.Errorf("%w: expected list<map<string,string>>", errThriftMalformed)
+	}
+	if err := checkContainerSize(size); err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]string, 0, min(size, maxThriftPrealloc))
+	for i := int32(0); i < size; i++ {
+		item, err := t.readStringMap()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// checkContainerSize rejects element counts that are negative or larger than
+// any osquery reply, since the count comes straight off the socket.
+func checkContainerSize(size int32) error {
+	if size < 0 || size > maxThriftContainerItems {
+		return fmt.Errorf("%w: container size %d", errThriftMalformed, size)
+	}
+	return nil
+}
+
+func (t *thriftReader) skip(fieldType byte, depth int) error {
+	if depth > maxThriftDepth {
+		return fmt.Errorf("%w: nesting too deep", errThriftMalformed)
+	}
+
+	switch fieldType {
+	case thriftBool, thriftByte:
+		_, err := t.readByte()
+		return err
+	case thriftI16:
+		_, err := t.readI16()
+		return err
+	case thriftI32:
+		_, err := t.readI32()
+		return err
+	case thriftI64, thriftDouble:
+		_, err := t.readI64()
+		return err
+	case thriftString:
+		_, err := t.readString()
+		return err
+	case thriftStruct:
+		return t.readStruct(func(fieldType byte, _ int16) (bool, error) {
+			return true, t.skip(fieldType, depth+1)
+		})
+	case thriftMap:
+		keyType, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		valueType, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		size, err := t.readI32()
+		if err != nil {
+			return err
+		}
+		if err := checkContainerSize(size); err != nil {
+			return err
+		}
+		for i := int32(0); i < size; i++ {
+			if err := t.skip(keyType, depth+1); err != nil {
+				return err
+			}
+			if err := t.skip(valueType, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftSet, thriftList:
+		elemType, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		size, err := t.readI32()
+		if err != nil {
+			return err
+		}
+		if err := checkContainerSize(size); err != nil {
+			return err
+		}
+		for i := int32(0); i < size; i++ {
+			if err := t.skip(elemType, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown field type %d", errThriftMalformed, fieldType)
+	}
+}
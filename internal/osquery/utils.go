@@ -1,26 +1,80 @@
 package osquery
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
-func CheckOsqueryInstallation() error {
-	cmd := exec.Command("osqueryi", "--version")
-	output, err := cmd.CombinedOutput()
+var (
+	ErrNotInstalled       = errors.New("osquery is not installed or not in PATH. Please install osquery: https://osquery.io/downloads")
+	ErrUnsupportedVersion = errors.New("unsupported osquery version")
+)
+
+const installationCheckTimeout = 10 * time.Second
+
+type InstallationInfo struct {
+	BinaryPath    string  `json:"binary_path"`
+	Version       Version `json:"version"`
+	RawVersion    string  `json:"raw_version"`
+	BuildPlatform string  `json:"build_platform,omitempty"`
+	BuildDistro   string  `json:"build_distro,omitempty"`
+}
+
+func CheckOsqueryInstallation(ctx context.Context, binaryPath string, minVersion Version) (InstallationInfo, error) {
+	info := InstallationInfo{}
 
+	if binaryPath == "" {
+		binaryPath = "osqueryi"
+	}
+
+	resolved, err := exec.LookPath(binaryPath)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no such file") {
-			return fmt.Errorf("osquery is not installed or not in PATH. Please install osquery: https://osquery.io/downloads")
+		if errors.Is(err, exec.ErrNotFound) {
+			return info, fmt.Errorf("%w: %s", ErrNotInstalled, binaryPath)
 		}
+		return info, fmt.Errorf("error resolving osquery binary %s: %w", binaryPath, err)
+	}
+	info.BinaryPath = resolved
+
+	ctx, cancel := context.WithTimeout(ctx, installationCheckTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, resolved, "--version").CombinedOutput()
+	if err != nil {
+		return info, fmt.Errorf("error checking osquery installation: %w (output: %s)", err, string(output))
+	}
+
+	info.RawVersion = strings.TrimSpace(string(output))
+	if !strings.Contains(info.RawVersion, "version") {
+		return info, fmt.Errorf("osquery appears to be installed but returned unexpected output: %s", info.RawVersion)
+	}
+
+	info.Version, err = ParseVersion(info.RawVersion)
+	if err != nil {
+		return info, fmt.Errorf("failed to parse osquery version: %w", err)
+	}
 
-		return fmt.Errorf("error checking osquery installation: %w (output: %s)", err, string(output))
+	// Build details are informational only, so a failure here is not fatal.
+	rows, err := NewExecQuerier(resolved, installationCheckTimeout, 0).Query(ctx, "SELECT build_platform, build_distro FROM osquery_info;")
+	if err == nil && len(rows) > 0 {
+		info.BuildPlatform = Row(rows[0]).String("build_platform")
+		info.BuildDistro = Row(rows[0]).String("build_distro")
 	}
 
-	if !strings.Contains(string(output), "osqueryi version") {
-		return fmt.Errorf("osquery appears to be installed but returned unexpected output: %s", string(output))
+	if err := RequireVersion(info.Version, minVersion); err != nil {
+		return info, err
 	}
 
+	return info, nil
+}
+
+func RequireVersion(version, minVersion Version) error {
+	if !minVersion.IsZero() && !version.AtLeast(minVersion) {
+		return fmt.Errorf("%w: found %s, need at least %s", ErrUnsupportedVersion, version, minVersion)
+	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package osquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?(?:[-+]([0-9A-Za-z.\-+]+))?`)
+
+type Version struct {
+	Major int    `json:"major"`
+	Minor int    `json:"minor"`
+	Patch int    `json:"patch"`
+	Build string `json:"build,omitempty"`
+}
+
+// ParseVersion extracts the first semantic version in s, so it accepts both
+// a bare "5.11.0" and full output such as "osqueryi version 5.11.0-12-gabc".
+func ParseVersion(s string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, fmt.Errorf("no version found in %q", s)
+	}
+
+	v := Version{Build: match[4]}
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
+	}
+	return v, nil
+}
+
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare orders versions by major, minor and patch; build metadata is ignored.
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) AtLeast(min Version) bool {
+	return v.Compare(min) >= 0
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Build != "" {
+		s += "-" + v.Build
+	}
+	return s
+}
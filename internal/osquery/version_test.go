@@ -0,0 +1,58 @@
+package osquery
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"5.11.0":                         {Major: 5, Minor: 11},
+		"v4.9":                           {Major: 4, Minor: 9},
+		"osqueryi version 5.12.1":        {Major: 5, Minor: 12, Patch: 1},
+		"osqueryi version 5.11.0-12-gab": {Major: 5, Minor: 11, Build: "12-gab"},
+	}
+	for input, want := range tests {
+		got, err := ParseVersion(input)
+		if err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %+v, %v; want %+v", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseVersion("osqueryi version unknown"); err == nil {
+		t.Error("ParseVersion accepted a string without a version")
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b Version
+		want int
+	}{
+		{Version{Major: 5, Minor: 11}, Version{Major: 5, Minor: 11, Build: "dev"}, 0},
+		{Version{Major: 5, Minor: 9, Patch: 1}, Version{Major: 5, Minor: 10}, -1},
+		{Version{Major: 10}, Version{Major: 9, Minor: 99}, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRequireVersion(t *testing.T) {
+	minimum := Version{Major: 4}
+
+	if err := RequireVersion(Version{Major: 5, Minor: 11}, minimum); err != nil {
+		t.Errorf("newer version rejected: %v", err)
+	}
+	if err := RequireVersion(Version{Major: 4}, minimum); err != nil {
+		t.Errorf("exact minimum rejected: %v", err)
+	}
+	if err := RequireVersion(Version{Major: 3, Minor: 3, Patch: 2}, minimum); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("older version: err = %v, want %v", err, ErrUnsupportedVersion)
+	}
+	if err := RequireVersion(Version{Major: 1}, Version{}); err != nil {
+		t.Errorf("zero minimum rejected a version: %v", err)
+	}
+}
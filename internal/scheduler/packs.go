@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Siddharth9890/osquery-mvp/internal/database"
+	"github.com/Siddharth9890/osquery-mvp/internal/osquery"
+	"github.com/Siddharth9890/osquery-mvp/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type PackScheduler struct {
+	client         *osquery.OsqueryClient
+	dbService      database.PackStore
+	packs          []osquery.Pack
+	hostIdentifier string
+}
+
+func NewPackScheduler(client *osquery.OsqueryClient, dbService database.PackStore, packs []osquery.Pack) *PackScheduler {
+	return &PackScheduler{
+		client:    client,
+		dbService: dbService,
+		packs:     packs,
+	}
+}
+
+// Run starts one loop per eligible pack query and blocks until ctx is done.
+// Results are stored under the host's hardware_uuid, like its snapshots, so
+// the packs are not scheduled until it is known.
+func (s *PackScheduler) Run(ctx context.Context) {
+	log := logger.Log
+	version := s.client.OsqueryVersion()
+
+	hostIdentifier, err := s.client.GetHardwareUUID(ctx)
+	if err != nil {
+		log.Error("Failed to identify host, not scheduling packs",
+			zap.Error(err))
+		return
+	}
+	s.hostIdentifier = hostIdentifier
+
+	var wg sync.WaitGroup
+	for _, pack := range s.packs {
+		if !osquery.MatchesPlatform(pack.Platform, runtime.GOOS) || !osquery.MeetsVersion(pack.Version, version) {
+			log.Info("Skipping pack not applicable to this host",
+				zap.String("pack", pack.Name),
+				zap.String("platform", pack.Platform),
+				zap.String("version", pack.Version))
+			continue
+		}
+
+		if !s.discover(ctx, pack) {
+			log.Info("Skipping pack whose discovery queries returned no rows",
+				zap.String("pack", pack.Name))
+			continue
+		}
+
+		for _, query := range pack.SortedQueries() {
+			if !osquery.MatchesPlatform(query.Platform, runtime.GOOS) || !osquery.MeetsVersion(query.Version, version) {
+				log.Debug("Skipping pack query not applicable to this host",
+					zap.String("pack", pack.Name),
+					zap.String("query", query.Name))
+				continue
+			}
+
+			wg.Add(1)
+			go func(packName string, query osquery.PackQuery) {
+				defer wg.Done()
+				s.schedule(ctx, packName, query)
+			}(pack.Name, query)
+		}
+	}
+
+	wg.Wait()
+}
+
+func (s *PackScheduler) discover(ctx context.Context, pack osquery.Pack) bool {
+	for _, query := range pack.Discovery {
+		result, err := s.client.Query(ctx, query)
+		if err != nil {
+			logger.Log.Warn("Pack discovery query failed",
+				zap.String("pack", pack.Name),
+				zap.String("query", query),
+				zap.Error(err))
+			return false
+		}
+		if len(result.Rows) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *PackScheduler) schedule(ctx context.Context, packName string, query osquery.PackQuery) {
+	interval := time.Duration(query.Interval) * time.Second
+	logger.Log.Info("Scheduling pack query",
+		zap.String("pack", packName),
+		zap.String("query", query.Name),
+		zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runQuery(ctx, packName, query)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *PackScheduler) runQuery(ctx context.Context, packName string, query osquery.PackQuery) {
+	log := logger.Log.With(
+		zap.String("pack", packName),
+		zap.String("query", query.Name),
+	)
+
+	result, err := s.client.Query(ctx, query.Query)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("Pack query failed",
+				zap.Error(err))
+		}
+		return
+	}
+
+	var events []osquery.QueryEvent
+	if !query.Snapshot {
+		previous, runs, err := s.dbService.GetPreviousPackResult(s.hostIdentifier, packName, query.Name)
+		if err != nil {
+			log.Error("Failed to load previous pack query result",
+				zap.Error(err))
+			return
+		}
+
+		logRemoved := query.Removed == nil || *query.Removed
+		diff := osquery.DiffRows(previous, result.Rows)
+		events = diff.Events(osquery.PackQueryName(packName, query.Name), s.hostIdentifier, runs, time.Now(), logRemoved)
+	}
+
+	if err := s.dbService.StorePackResult(s.hostIdentifier, packName, query.Name, query.Snapshot, result.Rows, events); err != nil {
+		log.Error("Failed to store pack query result",
+			zap.Error(err))
+		return
+	}
+
+	log.Debug("Stored pack query result",
+		zap.Int("row_count", len(result.Rows)),
+		zap.Int("event_count", len(events)))
+}
@@ -2,25 +2,30 @@ package ui
 
 import (
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
-
-	"github.com/Siddharth9890/osquery-mvp/internal/database"
 )
 
 type Handler struct {
-	dbService  *database.Service
 	templates  *template.Template
 	apiBaseURL string
 }
 
 type PageData struct {
-	SystemInfo    SystemInfo
-	InstalledApps []InstalledApp
-	LastUpdated   string
-	Error         string
+	SystemInfo     SystemInfo
+	HostInfo       HostInfo
+	InstalledApps  []InstalledApp
+	ListeningPorts []ListeningPort
+	Certificates   []ExpiringCertificate
+	Mounts         []Mount
+	Health         Health
+	LastUpdated    string
+	Error          string
 }
 
 type SystemInfo struct {
@@ -28,21 +33,76 @@ type SystemInfo struct {
 	OSName         string
 	OSPlatform     string
 	OsqueryVersion string
+	OSBuild        string
+	OSArch         string
+	KernelVersion  string
+}
+
+type HostInfo struct {
+	Hostname       string
+	HardwareUUID   string
+	CPUBrand       string
+	CPUCores       string
+	PhysicalMemory string
+	Hardware       string
+	HardwareSerial string
+	Uptime         string
 }
 
 type InstalledApp struct {
 	Name    string
 	Version string
+	Source  string
+	Arch    string
+	Vendor  string
+}
+
+type ListeningPort struct {
+	Address     string `json:"address"`
+	Port        int    `json:"port"`
+	Protocol    string `json:"protocol"`
+	Interface   string `json:"interface"`
+	ProcessName string `json:"process_name"`
+	ProcessPath string `json:"process_path"`
+	PID         int64  `json:"pid"`
+	Username    string `json:"username"`
+}
+
+type ExpiringCertificate struct {
+	CommonName string    `json:"common_name"`
+	Issuer     string    `json:"issuer"`
+	Serial     string    `json:"serial"`
+	SHA1       string    `json:"sha1"`
+	CA         bool      `json:"ca"`
+	Path       string    `json:"path"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Expired    bool      `json:"expired"`
+}
+
+type Mount struct {
+	Path              string  `json:"path"`
+	Device            string  `json:"device"`
+	Type              string  `json:"type"`
+	Blocks            int64   `json:"blocks"`
+	BlockSize         int64   `json:"blocks_size"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+	OverThreshold     bool    `json:"over_threshold"`
+	Size              string  `json:"-"`
+}
+
+type Health struct {
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons"`
 }
 
-func NewHandler(dbService *database.Service, apiBaseURL string) (*Handler, error) {
+func NewHandler(apiBaseURL string) (*Handler, error) {
 	tmpl, err := template.ParseGlob("ui/templates/*.html")
 	if err != nil {
 		return nil, err
 	}
 
 	return &Handler{
-		dbService:  dbService,
 		templates:  tmpl,
 		apiBaseURL: apiBaseURL,
 	}, nil
@@ -62,12 +122,31 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		Data    struct {
 			ID             int       `json:"id"`
 			OSVersion      string    `json:"os_version"`
+			OSName         string    `json:"os_name"`
+			OSPlatform     string    `json:"os_platform"`
 			OsqueryVersion string    `json:"osquery_version"`
+			OSBuild        string    `json:"os_build"`
+			OSArch         string    `json:"os_arch"`
+			Hostname       string    `json:"hostname"`
+			HardwareUUID   string    `json:"hardware_uuid"`
+			CPUBrand       string    `json:"cpu_brand"`
+			CPUPhysical    int       `json:"cpu_physical_cores"`
+			CPULogical     int       `json:"cpu_logical_cores"`
+			PhysicalMemory int64     `json:"physical_memory"`
+			HardwareVendor string    `json:"hardware_vendor"`
+			HardwareModel  string    `json:"hardware_model"`
+			HardwareSerial string    `json:"hardware_serial"`
+			KernelVersion  string    `json:"kernel_version"`
+			UptimeSeconds  int64     `json:"uptime_seconds"`
 			CollectedAt    time.Time `json:"collected_at"`
 			Apps           []struct {
 				Name    string `json:"name"`
 				Version string `json:"version"`
+				Source  string `json:"source"`
+				Arch    string `json:"arch"`
+				Vendor  string `json:"vendor"`
 			} `json:"installed_apps"`
+			Health Health `json:"health"`
 		} `json:"data"`
 		Error string `json:"error,omitempty"`
 	}
@@ -83,9 +162,12 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	osName, osPlatform, err := h.dbService.GetOSDetails()
-	if err != nil {
-		log.Printf("Error getting OS details: %v", err)
+	osName, osPlatform := apiResp.Data.OSName, apiResp.Data.OSPlatform
+	if osName == "" {
+		osName = "Unknown"
+	}
+	if osPlatform == "" {
+		osPlatform = "Unknown"
 	}
 
 	sysInfo := SystemInfo{
@@ -93,6 +175,20 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		OSName:         osName,
 		OSPlatform:     osPlatform,
 		OsqueryVersion: apiResp.Data.OsqueryVersion,
+		OSBuild:        apiResp.Data.OSBuild,
+		OSArch:         apiResp.Data.OSArch,
+		KernelVersion:  apiResp.Data.KernelVersion,
+	}
+
+	hostInfo := HostInfo{
+		Hostname:       apiResp.Data.Hostname,
+		HardwareUUID:   apiResp.Data.HardwareUUID,
+		CPUBrand:       apiResp.Data.CPUBrand,
+		CPUCores:       fmt.Sprintf("%d physical / %d logical", apiResp.Data.CPUPhysical, apiResp.Data.CPULogical),
+		PhysicalMemory: formatBytes(apiResp.Data.PhysicalMemory),
+		Hardware:       strings.TrimSpace(apiResp.Data.HardwareVendor + " " + apiResp.Data.HardwareModel),
+		HardwareSerial: apiResp.Data.HardwareSerial,
+		Uptime:         (time.Duration(apiResp.Data.UptimeSeconds) * time.Second).String(),
 	}
 
 	apps := make([]InstalledApp, 0, len(apiResp.Data.Apps))
@@ -100,15 +196,43 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		apps = append(apps, InstalledApp{
 			Name:    app.Name,
 			Version: app.Version,
+			Source:  app.Source,
+			Arch:    app.Arch,
+			Vendor:  app.Vendor,
 		})
 	}
 
 	lastUpdated := apiResp.Data.CollectedAt.Format("Jan 02, 2006 15:04:05")
 
+	var ports []ListeningPort
+	if err := h.fetchAPI("/listening_ports", &ports); err != nil {
+		log.Printf("Error fetching listening ports: %v", err)
+	}
+
+	var certificates []ExpiringCertificate
+	if err := h.fetchAPI("/certificates/expiring", &certificates); err != nil {
+		log.Printf("Error fetching expiring certificates: %v", err)
+	}
+
+	var mounts struct {
+		Mounts []Mount `json:"mounts"`
+	}
+	if err := h.fetchAPI("/mounts", &mounts); err != nil {
+		log.Printf("Error fetching mounts: %v", err)
+	}
+	for i := range mounts.Mounts {
+		mounts.Mounts[i].Size = formatBytes(mounts.Mounts[i].Blocks * mounts.Mounts[i].BlockSize)
+	}
+
 	data := PageData{
-		SystemInfo:    sysInfo,
-		InstalledApps: apps,
-		LastUpdated:   lastUpdated,
+		SystemInfo:     sysInfo,
+		HostInfo:       hostInfo,
+		InstalledApps:  apps,
+		ListeningPorts: ports,
+		Certificates:   certificates,
+		Mounts:         mounts.Mounts,
+		Health:         apiResp.Data.Health,
+		LastUpdated:    lastUpdated,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "dashboard.html", data); err != nil {
@@ -117,10 +241,219 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type ProcessPageData struct {
+	Sort      string
+	Processes []ProcessRow
+	Error     string
+}
+
+type ProcessRow struct {
+	Indent       int
+	PID          int64
+	Name         string
+	Path         string
+	Cmdline      string
+	UID          int64
+	ResidentSize string
+	CPUTime      string
+}
+
+type processNode struct {
+	PID          int64          `json:"pid"`
+	Name         string         `json:"name"`
+	Path         string         `json:"path"`
+	Cmdline      string         `json:"cmdline"`
+	UID          int64          `json:"uid"`
+	ResidentSize int64          `json:"resident_size"`
+	CPUTime      int64          `json:"cpu_time"`
+	Children     []*processNode `json:"children"`
+}
+
+func (h *Handler) Processes(w http.ResponseWriter, r *http.Request) {
+	sortBy := r.URL.Query().Get("sort")
+	switch sortBy {
+	case "memory", "cpu":
+	default:
+		sortBy = "pid"
+	}
+
+	var tree []*processNode
+	if err := h.fetchAPI("/process_tree?sort="+sortBy, &tree); err != nil {
+		log.Printf("Error fetching process tree: %v", err)
+		renderErrorPage(h.templates, w, "Failed to fetch process tree from API")
+		return
+	}
+
+	data := ProcessPageData{Sort: sortBy}
+
+	// The tree is flattened depth first so each row can be indented by depth.
+	var flatten func(nodes []*processNode, depth int)
+	flatten = func(nodes []*processNode, depth int) {
+		for _, node := range nodes {
+			data.Processes = append(data.Processes, ProcessRow{
+				Indent:       12 + depth*20,
+				PID:          node.PID,
+				Name:         node.Name,
+				Path:         node.Path,
+				Cmdline:      node.Cmdline,
+				UID:          node.UID,
+				ResidentSize: formatBytes(node.ResidentSize),
+				CPUTime:      (time.Duration(node.CPUTime) * time.Millisecond).String(),
+			})
+			flatten(node.Children, depth+1)
+		}
+	}
+	flatten(tree, 0)
+
+	if err := h.templates.ExecuteTemplate(w, "processes.html", data); err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+type ContainerPageData struct {
+	Containers []ContainerRow
+	Images     []ImageRow
+}
+
+type ContainerRow struct {
+	Name       string
+	Image      string
+	State      string
+	Status     string
+	Privileged bool
+	Ports      string
+	Labels     string
+}
+
+type ImageRow struct {
+	ID      string
+	Tags    string
+	Size    string
+	Created string
+}
+
+func (h *Handler) Containers(w http.ResponseWriter, r *http.Request) {
+	var containers []struct {
+		Name       string            `json:"name"`
+		Image      string            `json:"image"`
+		State      string            `json:"state"`
+		Status     string            `json:"status"`
+		Privileged bool              `json:"privileged"`
+		Labels     map[string]string `json:"labels"`
+		Ports      []struct {
+			Type     string `json:"type"`
+			Port     int    `json:"port"`
+			HostIP   string `json:"host_ip"`
+			HostPort int    `json:"host_port"`
+		} `json:"ports"`
+	}
+	if err := h.fetchAPI("/containers", &containers); err != nil {
+		log.Printf("Error fetching containers: %v", err)
+		renderErrorPage(h.templates, w, "Failed to fetch containers from API")
+		return
+	}
+
+	var images []struct {
+		ID        string   `json:"id"`
+		Created   int64    `json:"created"`
+		SizeBytes int64    `json:"size_bytes"`
+		Tags      []string `json:"tags"`
+	}
+	if err := h.fetchAPI("/images", &images); err != nil {
+		log.Printf("Error fetching images: %v", err)
+		renderErrorPage(h.templates, w, "Failed to fetch images from API")
+		return
+	}
+
+	var data ContainerPageData
+	for _, container := range containers {
+		var ports []string
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				ports = append(ports, fmt.Sprintf("%s:%d->%d/%s", port.HostIP, port.HostPort, port.Port, port.Type))
+			} else {
+				ports = append(ports, fmt.Sprintf("%d/%s", port.Port, port.Type))
+			}
+		}
+
+		var labels []string
+		for key, value := range container.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+
+		data.Containers = append(data.Containers, ContainerRow{
+			Name:       strings.TrimPrefix(container.Name, "/"),
+			Image:      container.Image,
+			State:      container.State,
+			Status:     container.Status,
+			Privileged: container.Privileged,
+			Ports:      strings.Join(ports, ", "),
+			Labels:     strings.Join(labels, ", "),
+		})
+	}
+
+	for _, image := range images {
+		id := strings.TrimPrefix(image.ID, "sha256:")
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		data.Images = append(data.Images, ImageRow{
+			ID:      id,
+			Tags:    strings.Join(image.Tags, ", "),
+			Size:    formatBytes(image.SizeBytes),
+			Created: time.Unix(image.Created, 0).Format("Jan 02, 2006 15:04"),
+		})
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "containers.html", data); err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) Assets(w http.ResponseWriter, r *http.Request) {
 	http.StripPrefix("/assets/", http.FileServer(http.Dir("ui/assets"))).ServeHTTP(w, r)
 }
 
+// fetchAPI decodes the data of a successful API response into dest.
+func (h *Handler) fetchAPI(path string, dest interface{}) error {
+	resp, err := http.Get(h.apiBaseURL + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return err
+	}
+	if !apiResp.Success {
+		return fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	return json.Unmarshal(apiResp.Data, dest)
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func renderErrorPage(tmpl *template.Template, w http.ResponseWriter, errorMsg string) {
 	data := PageData{
 		Error: errorMsg,